@@ -6,9 +6,12 @@ import (
 	"os"
 	"os/signal"
 	"referee/internal/arbitrage"
+	"referee/internal/clock"
 	"referee/internal/config"
 	"referee/internal/database"
 	"referee/internal/exchange"
+	"referee/internal/instrument"
+	"referee/internal/tickfilter"
 	"syscall"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -31,6 +34,17 @@ func main() {
 	}
 	logger.Info("Configuration loaded successfully")
 
+	// Load the instrument registry used to resolve symbols across exchanges
+	instruments, err := instrument.Load(&cfg)
+	if err != nil {
+		logger.Error("Failed to load instruments", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("Instruments loaded", "count", len(instruments.All()))
+
+	// All components share the system clock in live mode
+	clk := clock.NewReal()
+
 	// Create database connection pool
 	pool, err := pgxpool.New(context.Background(), cfg.Database.DSN())
 	if err != nil {
@@ -41,7 +55,7 @@ func main() {
 	logger.Info("Database connection established")
 
 	// Create repository
-	repo := &database.PostgresRepository{Pool: pool}
+	repo := &database.PostgresRepository{Pool: pool, Clock: clk}
 
 	// Run database migrations
 	if err := repo.Migrate(context.Background()); err != nil {
@@ -50,14 +64,25 @@ func main() {
 	}
 	logger.Info("Database migrations completed successfully")
 
+	// Scope the IDs the engine assigns, such as ledger entry IDs, to this run
+	if err := repo.StartRun(context.Background()); err != nil {
+		logger.Error("Failed to start run", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("Run started", "runID", repo.RunID)
+
 	// Create arbitrage engine
-	engine := arbitrage.NewArbitrageEngine(logger, repo, &cfg)
+	engine, err := arbitrage.NewArbitrageEngine(logger, repo, &cfg, clk, instruments)
+	if err != nil {
+		logger.Error("Failed to create arbitrage engine", "error", err)
+		os.Exit(1)
+	}
 	logger.Info("Arbitrage engine initialized")
 
 	// Create exchange clients based on configuration
 	clients := make([]exchange.ExchangeClient, 0, len(cfg.Exchanges))
 	for name, exchangeCfg := range cfg.Exchanges {
-		client, err := exchange.NewClient(name, logger, &exchangeCfg)
+		client, err := exchange.NewClient(name, logger, &exchangeCfg, clk, instruments)
 		if err != nil {
 			logger.Error("Failed to create exchange client", "exchange", name, "error", err)
 			os.Exit(1)
@@ -73,19 +98,40 @@ func main() {
 	// Use an errgroup to manage goroutines
 	eg, gCtx := errgroup.WithContext(ctx)
 
-	// Create the fan-in channel for price ticks
+	// Create the fan-in channel for price ticks, and the channel of validated ticks
+	// the engine consumes
+	rawChan := make(chan model.PriceTick, 100)
 	priceChan := make(chan model.PriceTick, 100)
 
+	// Drop malformed and outlier ticks before they reach the engine
+	tickFilter := tickfilter.New(logger, cfg.Arbitrage.TickFilter, clk)
+	eg.Go(func() error {
+		return tickFilter.Run(gCtx, rawChan, priceChan)
+	})
+
 	// Start the arbitrage engine goroutine
 	eg.Go(func() error {
 		logger.Info("Starting arbitrage engine")
+		err := engine.Run(gCtx, priceChan)
+		logger.Info("Arbitrage engine shutting down")
+		return err
+	})
+
+	// SIGUSR1 engages the kill switch and SIGUSR2 releases it; data keeps being recorded
+	eg.Go(func() error {
+		killSwitch := make(chan os.Signal, 1)
+		signal.Notify(killSwitch, syscall.SIGUSR1, syscall.SIGUSR2)
+		defer signal.Stop(killSwitch)
 		for {
 			select {
 			case <-gCtx.Done():
-				logger.Info("Arbitrage engine shutting down")
-				return gCtx.Err()
-			case tick := <-priceChan:
-				engine.ProcessTick(gCtx, tick)
+				return nil
+			case sig := <-killSwitch:
+				if sig == syscall.SIGUSR1 {
+					engine.Halt(gCtx, "halted by signal")
+				} else {
+					engine.Resume(gCtx)
+				}
 			}
 		}
 	})
@@ -95,7 +141,7 @@ func main() {
 		c := client // capture range variable
 		eg.Go(func() error {
 			logger.Info("Starting exchange client", "exchange", c.GetName())
-			if err := c.StartStream(gCtx, priceChan, cfg.Arbitrage.TradingPair); err != nil {
+			if err := c.StartStream(gCtx, rawChan, cfg.PairsFor(c.GetName())); err != nil {
 				logger.Error("Exchange client error", "exchange", c.GetName(), "error", err)
 				return err
 			}
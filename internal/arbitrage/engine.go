@@ -3,100 +3,562 @@ package arbitrage
 import (
 	"context"
 	"log/slog"
+	"referee/internal/clock"
 	"referee/internal/config"
 	"referee/internal/database"
+	"referee/internal/fees"
+	"referee/internal/instrument"
+	"referee/internal/inventory"
+	"referee/internal/ledger"
 	"referee/internal/model"
+	"referee/internal/risk"
 	"time"
 )
 
+// Trade statuses recorded once the simulated latency has elapsed.
+const (
+	TradeStatusExecuted = "executed"
+	TradeStatusMissed   = "missed"
+)
+
+// Trade types distinguishing how an opportunity was found.
+const (
+	TradeTypeSpatial    = "spatial"    // Buy on one exchange, sell on another
+	TradeTypeTriangular = "triangular" // Cycle through three pairs on one exchange
+)
+
+// Risk event actions.
+const (
+	RiskActionBlocked = "blocked" // A limit stopped a trade from being submitted
+	RiskActionHalted  = "halted"  // The kill switch was engaged
+	RiskActionResumed = "resumed" // The kill switch was released
+)
+
+// staleCheckInterval is how often the engine re-evaluates quote staleness when no ticks arrive.
+const staleCheckInterval = time.Second
+
 // ArbitrageEngine holds the logic for identifying and executing arbitrage opportunities.
 type ArbitrageEngine struct {
-	logger       *slog.Logger
-	repo         database.Repository
-	cfg          *config.Config
-	latestPrices map[string]model.PriceTick
+	logger      *slog.Logger
+	repo        database.Repository
+	cfg         *config.Config
+	clock       clock.Clock
+	instruments *instrument.Registry
+	market      *Market
+	strategies  []Strategy
+	episodes    *episodeTracker
+	fees        *fees.Model
+	risk        *risk.Manager
+	inventory   *inventory.Inventory  // Nil unless inventory simulation is enabled
+	rebalancer  *inventory.Rebalancer // Nil unless rebalancing is enabled
+	// The ledger books the inventory's trades and transfers; nil unless inventory is enabled
+	ledger       *ledger.Ledger
+	opening      model.LedgerEntry
+	nextSnapshot time.Time
+	scheduler    scheduler
 }
 
-// NewArbitrageEngine creates a new instance of the ArbitrageEngine.
-func NewArbitrageEngine(logger *slog.Logger, repo database.Repository, cfg *config.Config) *ArbitrageEngine {
-	return &ArbitrageEngine{
-		logger:       logger,
-		repo:         repo,
-		cfg:          cfg,
-		latestPrices: make(map[string]model.PriceTick),
+// NewArbitrageEngine creates a new instance of the ArbitrageEngine running the configured strategies.
+func NewArbitrageEngine(logger *slog.Logger, repo database.Repository, cfg *config.Config, clk clock.Clock, instruments *instrument.Registry) (*ArbitrageEngine, error) {
+	e := &ArbitrageEngine{
+		logger:      logger,
+		repo:        repo,
+		cfg:         cfg,
+		clock:       clk,
+		instruments: instruments,
+		market:      newMarket(),
+		fees:        fees.NewModel(cfg, clk),
+		risk:        risk.New(cfg.Arbitrage.Risk),
+	}
+	maxExecutions := cfg.Arbitrage.Episodes.MaxExecutions
+	if maxExecutions == 0 {
+		maxExecutions = 1
+	}
+	e.episodes = newEpisodeTracker(maxExecutions)
+	if cfg.Arbitrage.Inventory.Enabled {
+		balances := make(map[string]map[string]float64, len(cfg.Exchanges))
+		for exchange, exchangeCfg := range cfg.Exchanges {
+			balances[exchange] = exchangeCfg.Balances
+		}
+		e.inventory = inventory.New(balances)
+		e.ledger = ledger.New()
+		opening, err := e.ledger.Record(ledger.OpeningEntry(clk.Now(), e.inventory.Snapshot(clk.Now())))
+		if err != nil {
+			return nil, err
+		}
+		e.opening = opening
+		e.nextSnapshot = clk.Now()
+
+		if rebalance := cfg.Arbitrage.Inventory.Rebalance; rebalance.Enabled {
+			costs := make(map[string]inventory.TransferCost, len(rebalance.Transfers))
+			for asset, transfer := range rebalance.Transfers {
+				costs[asset] = inventory.TransferCost{Fee: transfer.Fee, Delay: time.Duration(transfer.DelayMS) * time.Millisecond}
+			}
+			e.rebalancer = inventory.NewRebalancer(rebalance.SkewThreshold, costs)
+		}
+	}
+
+	strategies, err := NewStrategies(logger, cfg, instruments, e.fees, e.inventory)
+	if err != nil {
+		return nil, err
+	}
+	e.strategies = strategies
+	return e, nil
+}
+
+// Run consumes price ticks until the context is cancelled. Pending executions are settled
+// as soon as their simulated latency elapses, without blocking tick processing.
+func (e *ArbitrageEngine) Run(ctx context.Context, priceChan <-chan model.PriceTick) error {
+	e.logBalances(ctx, e.clock.Now())
+	if e.ledger != nil {
+		e.logLedgerEntry(ctx, e.opening)
+	}
+	if halted, reason := e.risk.Halted(); halted {
+		e.logger.Warn("Trading halted, recording data only", "reason", reason)
+		e.logRiskEvent(ctx, model.RiskEvent{Timestamp: e.clock.Now(), Rule: risk.RuleKillSwitch, Action: RiskActionHalted, Detail: reason})
+	}
+	// Each timer is armed once and only re-armed when it fires or, for the scheduler,
+	// when the next due time changes, so waiters do not pile up on the clock
+	staleCheck := e.clock.After(staleCheckInterval)
+	var due <-chan time.Time
+	var dueAt time.Time
+	for {
+		if next, ok := e.scheduler.Next(); !ok {
+			due, dueAt = nil, time.Time{}
+		} else if due == nil || !next.Equal(dueAt) {
+			due, dueAt = e.clock.After(next.Sub(e.clock.Now())), next
+		}
+
+		select {
+		case <-staleCheck:
+			staleCheck = e.clock.After(staleCheckInterval)
+			e.checkStaleness(e.clock.Now())
+			e.markToMarket(ctx, e.clock.Now())
+		case <-ctx.Done():
+			// The context is done, so record the cancelled executions and open episodes without it
+			if pending := e.scheduler.Len(); pending > 0 {
+				e.logger.Info("Cancelling pending executions and transfers", "count", pending)
+			}
+			e.scheduler.CancelAll(context.WithoutCancel(ctx), e.clock.Now())
+			for _, ended := range e.episodes.endAll() {
+				e.logEpisode(context.WithoutCancel(ctx), ended)
+			}
+			return ctx.Err()
+		case tick := <-priceChan:
+			e.ProcessTick(ctx, tick)
+		case <-due:
+			due = nil
+			e.executeDue(ctx, e.clock.Now())
+		}
 	}
 }
 
 // ProcessTick processes a new price tick to check for arbitrage opportunities.
 func (e *ArbitrageEngine) ProcessTick(ctx context.Context, tick model.PriceTick) {
+	if _, ok := e.instruments.Get(tick.Pair); !ok {
+		e.logger.Warn("Dropping tick for unknown instrument", "exchange", tick.Exchange, "pair", tick.Pair)
+		return
+	}
+
+	// A virtual clock follows the timestamps of replayed ticks
+	if advancer, ok := e.clock.(clock.Advancer); ok && !tick.ReceivedAt.IsZero() {
+		advancer.AdvanceTo(tick.ReceivedAt)
+	}
+
 	// Log the incoming price tick
 	if err := e.repo.LogPriceTick(ctx, tick); err != nil {
 		e.logger.Error("Failed to log price tick", "error", err)
 	}
 
-	// Update the latest price for this exchange
-	e.latestPrices[tick.Exchange] = tick
+	// Settle executions whose latency elapsed before this tick arrived, against the
+	// market as it stood at that moment
+	e.executeDue(ctx, e.clock.Now())
+
+	// Update the latest price for this exchange, stamping ticks that carry no receive time
+	if tick.ReceivedAt.IsZero() {
+		tick.ReceivedAt = e.clock.Now()
+	}
+	e.market.update(tick)
+	e.checkStaleness(e.clock.Now())
+	e.markToMarket(ctx, e.clock.Now())
+
+	// Every strategy sees the same market. A persistent opportunity is detected on every
+	// tick until the books uncross, but only its first executions are submitted.
+	now := e.clock.Now()
+	seen := make(map[string]bool)
+	for _, strategy := range e.strategies {
+		for _, opportunity := range strategy.Detect(e.market, tick) {
+			ep := e.episodes.observe(now, strategy.Name(), opportunity)
+			seen[ep.key] = true
+			opportunity.Trade.EpisodeID = ep.ID
+			switch {
+			case opportunity.Reason != "":
+				e.logOpportunity(ctx, now, strategy.Name(), opportunity.Trade, opportunity.Reason)
+			case !e.episodes.allows(ep):
+				e.logOpportunity(ctx, now, strategy.Name(), opportunity.Trade, OpportunityDuplicate)
+			default:
+				if e.submit(ctx, strategy, opportunity) {
+					ep.Executions++
+				}
+			}
+		}
+	}
+	for _, ended := range e.episodes.end(now, quoteKey{Pair: tick.Pair, Exchange: tick.Exchange}, seen) {
+		e.logEpisode(ctx, ended)
+	}
+}
+
+// Halt engages the kill switch. Detection and recording carry on, but no further trades
+// are submitted until Resume is called; trades already submitted still settle.
+func (e *ArbitrageEngine) Halt(ctx context.Context, reason string) {
+	if !e.risk.Halt(reason) {
+		return
+	}
+	e.logger.Warn("Trading halted, recording data only", "reason", reason)
+	e.logRiskEvent(ctx, model.RiskEvent{Timestamp: e.clock.Now(), Rule: risk.RuleKillSwitch, Action: RiskActionHalted, Detail: reason})
+}
+
+// Resume releases the kill switch.
+func (e *ArbitrageEngine) Resume(ctx context.Context) {
+	if !e.risk.Resume() {
+		return
+	}
+	e.logger.Info("Trading resumed")
+	e.logRiskEvent(ctx, model.RiskEvent{Timestamp: e.clock.Now(), Rule: risk.RuleKillSwitch, Action: RiskActionResumed})
+}
+
+// submit schedules an opportunity for execution once the simulated latency has elapsed.
+// It returns false if the inventory cannot cover the trade or a risk limit blocks it.
+func (e *ArbitrageEngine) submit(ctx context.Context, strategy Strategy, opportunity Opportunity) bool {
+	detected := opportunity.Trade
+	detected.Strategy = strategy.Name()
+	detected.StrategyParams = strategy.Params()
+	detected.DetectedAt = e.clock.Now()
+	detected.DetectedBuyPrice = detected.BuyPrice
+	detected.DetectedSellPrice = detected.SellPrice
+
+	if e.inventory != nil {
+		if err := e.inventory.Check(detected.Legs); err != nil {
+			e.logger.Debug("Opportunity blocked by inventory", "strategy", detected.Strategy, "pair", detected.TradingPair, "error", err)
+			e.logOpportunity(ctx, detected.DetectedAt, detected.Strategy, detected, OpportunityInventoryBlocked)
+			return false
+		}
+	}
+	// The risk limits are in EUR, whatever the trade is denominated in
+	volumeEUR, valued := e.valueEUR(detected, detected.VolumeEUR)
+	if rule, detail, ok := e.risk.Check(detected.DetectedAt, detected, volumeEUR, valued); !ok {
+		e.logger.Info("Opportunity blocked by risk limit", "strategy", detected.Strategy, "pair", detected.TradingPair, "rule", rule, "detail", detail)
+		e.logRiskEvent(ctx, model.RiskEvent{
+			Timestamp:    detected.DetectedAt,
+			Rule:         rule,
+			Action:       RiskActionBlocked,
+			Strategy:     detected.Strategy,
+			TradingPair:  detected.TradingPair,
+			BuyExchange:  detected.BuyExchange,
+			SellExchange: detected.SellExchange,
+			Detail:       detail,
+		})
+		e.logOpportunity(ctx, detected.DetectedAt, detected.Strategy, detected, OpportunityRiskBlocked)
+		return false
+	}
+	e.risk.Submit(detected.DetectedAt, detected, volumeEUR)
+
+	e.logger.Info("Profitable arbitrage opportunity found",
+		"strategy", detected.Strategy,
+		"type", detected.TradeType,
+		"pair", detected.TradingPair,
+		"route", detected.Route,
+		"buyExchange", detected.BuyExchange,
+		"sellExchange", detected.SellExchange,
+		"buyPrice", detected.BuyPrice,
+		"sellPrice", detected.SellPrice,
+		"buyVWAP", detected.BuyVWAP,
+		"sellVWAP", detected.SellVWAP,
+		"slippage", detected.SlippageEUR,
+		"netProfit", detected.NetProfitEUR,
+	)
+
+	latency := time.Duration(e.cfg.Arbitrage.SimulatedLatencyMS) * time.Millisecond
+	e.scheduler.Schedule(detected.DetectedAt.Add(latency), func(ctx context.Context, now time.Time) {
+		e.settle(ctx, now, detected, volumeEUR, opportunity.Reprice)
+	}, func(ctx context.Context, now time.Time) {
+		e.risk.Settle(now, detected, volumeEUR, false, 0)
+		e.logOpportunity(ctx, detected.DetectedAt, detected.Strategy, detected, OpportunityCancelled)
+	})
+	return true
+}
+
+// executeDue settles every pending execution whose latency has elapsed by now.
+func (e *ArbitrageEngine) executeDue(ctx context.Context, now time.Time) {
+	e.scheduler.RunDue(ctx, now)
+}
 
-	// Check for arbitrage opportunities with other exchanges
-	for exchange, latestTick := range e.latestPrices {
-		if exchange == tick.Exchange {
-			continue // Skip comparing with itself
+// settle re-prices a detected opportunity against the latest market state and logs the
+// outcome. Opportunities that are no longer profitable are logged as missed with zero
+// PnL, since none of the legs would have filled. volumeEUR is the EUR volume the risk
+// manager was given on submission.
+func (e *ArbitrageEngine) settle(ctx context.Context, now time.Time, detected model.SimulatedTrade, volumeEUR float64, reprice func(*Market) (model.SimulatedTrade, bool)) {
+	e.checkStaleness(now)
+	trade, ok := reprice(e.market)
+	executable := ok && trade.NetProfitEUR > 0
+	if executable && e.inventory != nil {
+		// Earlier executions may have used up the balances this trade needs
+		if err := e.inventory.Apply(trade.Legs); err != nil {
+			e.logger.Info("Execution blocked by inventory", "strategy", detected.Strategy, "pair", detected.TradingPair, "error", err)
+			executable = false
+		}
+	}
+	if executable {
+		trade.Status = TradeStatusExecuted
+		e.recordVolume(trade)
+	} else {
+		trade = model.SimulatedTrade{
+			TradeType:    detected.TradeType,
+			Route:        detected.Route,
+			TradingPair:  detected.TradingPair,
+			BuyExchange:  detected.BuyExchange,
+			SellExchange: detected.SellExchange,
+			BuyPrice:     trade.BuyPrice,
+			SellPrice:    trade.SellPrice,
+			Status:       TradeStatusMissed,
 		}
+		e.logger.Info("Arbitrage opportunity evaporated during latency",
+			"strategy", detected.Strategy,
+			"type", detected.TradeType,
+			"pair", detected.TradingPair,
+			"route", detected.Route,
+			"buyExchange", detected.BuyExchange,
+			"sellExchange", detected.SellExchange,
+			"detectedNetProfit", detected.NetProfitEUR,
+		)
+	}
+	trade.Timestamp = now
+	trade.Strategy = detected.Strategy
+	trade.StrategyParams = detected.StrategyParams
+	trade.DetectedAt = detected.DetectedAt
+	trade.DetectedBuyPrice = detected.DetectedBuyPrice
+	trade.DetectedSellPrice = detected.DetectedSellPrice
+	trade.ProfitCurve = detected.ProfitCurve
+	trade.EpisodeID = detected.EpisodeID
 
-		// Check if we can buy on one exchange and sell on another
-		if tick.Ask < latestTick.Bid {
-			// Buy on tick.Exchange, sell on exchange
-			e.checkAndExecuteArbitrage(ctx, tick.Exchange, exchange, tick.Ask, latestTick.Bid)
-		} else if latestTick.Ask < tick.Bid {
-			// Buy on exchange, sell on tick.Exchange
-			e.checkAndExecuteArbitrage(ctx, exchange, tick.Exchange, latestTick.Ask, tick.Bid)
+	if err := e.repo.LogTrade(ctx, trade); err != nil {
+		e.logger.Error("Failed to log trade", "error", err)
+	}
+	executed := trade.Status == TradeStatusExecuted
+	var realizedEUR float64
+	if executed {
+		var ok bool
+		if realizedEUR, ok = e.valueEUR(trade, trade.NetProfitEUR); !ok {
+			e.logger.Warn("No price to value realized PnL", "strategy", trade.Strategy, "asset", trade.ProfitAsset())
 		}
 	}
+	e.risk.Settle(now, detected, volumeEUR, executed, realizedEUR)
+	reason := OpportunityMissed
+	if executed {
+		reason = OpportunityExecuted
+	}
+	e.logOpportunity(ctx, detected.DetectedAt, detected.Strategy, detected, reason)
+	if executed {
+		e.book(ctx, ledger.TradeEntry(now, trade), realizedEUR)
+		e.logBalances(ctx, now, trade.BuyExchange, trade.SellExchange)
+		e.rebalance(ctx, now)
+	}
 }
 
-// checkAndExecuteArbitrage checks if an arbitrage opportunity is profitable and executes it.
-func (e *ArbitrageEngine) checkAndExecuteArbitrage(ctx context.Context, buyExchange, sellExchange string, buyPrice, sellPrice float64) {
-	// Calculate profit using the formulas from the tech spec
-	volumeInCrypto := e.cfg.Arbitrage.SimulatedTradeVolumeEUR / buyPrice
-	grossProfitEUR := (sellPrice - buyPrice) * volumeInCrypto
+// logOpportunity records a trade detected by a strategy at the given time, with the
+// figures expected at detection and the reason it was or was not executed.
+func (e *ArbitrageEngine) logOpportunity(ctx context.Context, at time.Time, strategy string, trade model.SimulatedTrade, reason string) {
+	opportunity := model.Opportunity{
+		Timestamp:      at,
+		Strategy:       strategy,
+		TradeType:      trade.TradeType,
+		TradingPair:    trade.TradingPair,
+		Route:          trade.Route,
+		BuyExchange:    trade.BuyExchange,
+		SellExchange:   trade.SellExchange,
+		BuyPrice:       trade.BuyPrice,
+		SellPrice:      trade.SellPrice,
+		VolumeEUR:      trade.VolumeEUR,
+		GrossProfitEUR: trade.GrossProfitEUR,
+		TotalFeesEUR:   trade.TotalFeesEUR,
+		NetProfitEUR:   trade.NetProfitEUR,
+		Reason:         reason,
+		EpisodeID:      trade.EpisodeID,
+	}
+	if err := e.repo.LogOpportunity(ctx, opportunity); err != nil {
+		e.logger.Error("Failed to log opportunity", "error", err)
+	}
+}
 
-	// Calculate fees
-	buyLegFee := (buyPrice * volumeInCrypto) * (e.cfg.Exchanges[buyExchange].TakerFeePercent / 100)
-	sellLegFee := (sellPrice * volumeInCrypto) * (e.cfg.Exchanges[sellExchange].TakerFeePercent / 100)
-	totalFeesEUR := buyLegFee + sellLegFee + e.cfg.Arbitrage.NetworkWithdrawalFeeEUR
+// logRiskEvent records a blocked trade or a kill switch change.
+func (e *ArbitrageEngine) logRiskEvent(ctx context.Context, event model.RiskEvent) {
+	if err := e.repo.LogRiskEvent(ctx, event); err != nil {
+		e.logger.Error("Failed to log risk event", "error", err)
+	}
+}
 
-	// Calculate net profit
-	netProfitEUR := grossProfitEUR - totalFeesEUR
+// logEpisode records an episode that has ended.
+func (e *ArbitrageEngine) logEpisode(ctx context.Context, ep model.Episode) {
+	e.logger.Debug("Opportunity episode ended",
+		"strategy", ep.Strategy,
+		"pair", ep.TradingPair,
+		"route", ep.Route,
+		"buyExchange", ep.BuyExchange,
+		"sellExchange", ep.SellExchange,
+		"duration", ep.Duration(),
+		"ticks", ep.Ticks,
+		"executions", ep.Executions,
+	)
+	if err := e.repo.LogEpisode(ctx, ep); err != nil {
+		e.logger.Error("Failed to log episode", "error", err)
+	}
+}
+
+// recordVolume adds the notional of every leg to its exchange's 30-day volume, which
+// determines the fee tier of later trades.
+func (e *ArbitrageEngine) recordVolume(trade model.SimulatedTrade) {
+	for _, leg := range trade.Legs {
+		notional, ok := e.market.ValueIn("EUR", leg.QuoteAsset, leg.QuoteQty)
+		if !ok {
+			e.logger.Warn("No price to value traded volume", "exchange", leg.Exchange, "asset", leg.QuoteAsset)
+			continue
+		}
+		e.fees.Record(leg.Exchange, notional)
+	}
+}
+
+// valueEUR converts an amount denominated in the trade's profit asset into EUR at the
+// current mid price.
+func (e *ArbitrageEngine) valueEUR(trade model.SimulatedTrade, amount float64) (float64, bool) {
+	return e.market.ValueIn("EUR", trade.ProfitAsset(), amount)
+}
+
+// book records an entry in the ledger together with the PnL it realizes.
+func (e *ArbitrageEngine) book(ctx context.Context, entry model.LedgerEntry, realizedEUR float64) {
+	if e.ledger == nil {
+		return
+	}
+	entry, err := e.ledger.Record(entry)
+	if err != nil {
+		e.logger.Error("Failed to record ledger entry", "kind", entry.Kind, "error", err)
+		return
+	}
+	e.ledger.Realize(realizedEUR)
+	e.logLedgerEntry(ctx, entry)
+}
 
-	// Check if the trade is profitable
-	if netProfitEUR > 0 {
-		e.logger.Info("Profitable arbitrage opportunity found",
-			"buyExchange", buyExchange,
-			"sellExchange", sellExchange,
-			"buyPrice", buyPrice,
-			"sellPrice", sellPrice,
-			"netProfit", netProfitEUR,
+func (e *ArbitrageEngine) logLedgerEntry(ctx context.Context, entry model.LedgerEntry) {
+	if err := e.repo.LogLedgerEntry(ctx, entry); err != nil {
+		e.logger.Error("Failed to log ledger entry", "error", err)
+	}
+}
+
+// markToMarket values the ledger's holdings at the current mid prices once the snapshot
+// interval has elapsed. Snapshots are skipped while an asset has no fresh price.
+func (e *ArbitrageEngine) markToMarket(ctx context.Context, now time.Time) {
+	if e.ledger == nil || now.Before(e.nextSnapshot) {
+		return
+	}
+	snapshot, ok := e.ledger.MarkToMarket(now, func(asset string, amount float64) (float64, bool) {
+		return e.market.ValueIn("EUR", asset, amount)
+	})
+	if !ok {
+		return
+	}
+	interval := time.Duration(e.cfg.Arbitrage.Inventory.SnapshotIntervalMS) * time.Millisecond
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	e.nextSnapshot = now.Add(interval)
+
+	e.logger.Info("Marked inventory to market",
+		"equity", snapshot.EquityEUR,
+		"realizedPnL", snapshot.RealizedPnLEUR,
+		"unrealizedPnL", snapshot.UnrealizedPnLEUR,
+		"maxDrawdown", snapshot.MaxDrawdownEUR,
+	)
+	if err := e.repo.LogEquitySnapshot(ctx, snapshot); err != nil {
+		e.logger.Error("Failed to log equity snapshot", "error", err)
+	}
+}
+
+// rebalance starts the transfers needed to bring skewed inventory back into balance.
+// Transferred funds are unavailable for trading until they arrive at the destination.
+func (e *ArbitrageEngine) rebalance(ctx context.Context, now time.Time) {
+	if e.rebalancer == nil {
+		return
+	}
+	for _, transfer := range e.rebalancer.Plan(e.inventory, now) {
+		if err := e.inventory.StartTransfer(transfer); err != nil {
+			e.logger.Error("Failed to start transfer", "asset", transfer.Asset, "error", err)
+			continue
+		}
+		if feeEUR, ok := e.market.ValueIn("EUR", transfer.Asset, transfer.Fee); ok {
+			transfer.FeeEUR = feeEUR
+		} else {
+			e.logger.Warn("No price to value transfer fee", "asset", transfer.Asset)
+		}
+		e.logger.Info("Rebalancing inventory",
+			"asset", transfer.Asset,
+			"from", transfer.FromExchange,
+			"to", transfer.ToExchange,
+			"amount", transfer.Amount,
+			"fee", transfer.Fee,
+			"arrivesAt", transfer.ArrivesAt,
 		)
+		if err := e.repo.LogTransfer(ctx, transfer); err != nil {
+			e.logger.Error("Failed to log transfer", "error", err)
+		}
+		e.book(ctx, ledger.TransferOutEntry(transfer), -transfer.FeeEUR)
+		e.risk.Realize(now, -transfer.FeeEUR)
+		if amountEUR, ok := e.market.ValueIn("EUR", transfer.Asset, transfer.Amount); ok {
+			e.risk.Release(transfer.FromExchange, amountEUR)
+		} else {
+			e.logger.Warn("No price to value transfer for exposure", "asset", transfer.Asset)
+		}
+		e.logBalances(ctx, now, transfer.FromExchange)
+
+		e.scheduler.Schedule(transfer.ArrivesAt, func(ctx context.Context, now time.Time) {
+			e.inventory.CompleteTransfer(transfer)
+			e.book(ctx, ledger.TransferInEntry(transfer), 0)
+			e.logger.Info("Transfer arrived", "asset", transfer.Asset, "exchange", transfer.ToExchange, "amount", transfer.Amount-transfer.Fee)
+			e.logBalances(ctx, now, transfer.ToExchange)
+		}, nil)
+	}
+}
+
+// logBalances records the inventory of the given exchanges, or of all exchanges if none are given.
+func (e *ArbitrageEngine) logBalances(ctx context.Context, now time.Time, exchanges ...string) {
+	if e.inventory == nil {
+		return
+	}
+	if len(exchanges) == 2 && exchanges[0] == exchanges[1] {
+		exchanges = exchanges[:1]
+	}
+	if err := e.repo.LogBalances(ctx, e.inventory.Snapshot(now, exchanges...)); err != nil {
+		e.logger.Error("Failed to log balances", "error", err)
+	}
+}
+
+// checkStaleness marks quotes older than their exchange's configured maximum age as
+// stale, and logs every transition into or out of the stale state.
+func (e *ArbitrageEngine) checkStaleness(now time.Time) {
+	for pair, pairPrices := range e.market.prices {
+		for exchange, tick := range pairPrices {
+			key := quoteKey{Pair: pair, Exchange: exchange}
+			maxAge := e.cfg.MaxQuoteAge(exchange)
+			age := now.Sub(tick.ReceivedAt)
+			stale := maxAge > 0 && age > maxAge
 
-		// Simulate latency before logging the trade
-		time.Sleep(time.Duration(e.cfg.Arbitrage.SimulatedLatencyMS) * time.Millisecond)
-
-		// Log the trade
-		trade := model.SimulatedTrade{
-			Timestamp:      time.Now(),
-			TradingPair:    e.cfg.Arbitrage.TradingPair,
-			BuyExchange:    buyExchange,
-			SellExchange:   sellExchange,
-			BuyPrice:       buyPrice,
-			SellPrice:      sellPrice,
-			VolumeEUR:      e.cfg.Arbitrage.SimulatedTradeVolumeEUR,
-			GrossProfitEUR: grossProfitEUR,
-			TotalFeesEUR:   totalFeesEUR,
-			NetProfitEUR:   netProfitEUR,
-		}
-
-		if err := e.repo.LogTrade(ctx, trade); err != nil {
-			e.logger.Error("Failed to log trade", "error", err)
+			since, wasStale := e.market.staleSince[key]
+			switch {
+			case stale && !wasStale:
+				e.market.staleSince[key] = now
+				e.logger.Warn("Exchange quotes went stale", "exchange", exchange, "pair", pair, "age", age, "maxAge", maxAge)
+			case !stale && wasStale:
+				delete(e.market.staleSince, key)
+				e.logger.Info("Exchange quotes recovered", "exchange", exchange, "pair", pair, "staleFor", now.Sub(since))
+			}
 		}
 	}
 }
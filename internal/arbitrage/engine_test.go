@@ -4,11 +4,16 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"referee/internal/clock"
 	"referee/internal/config"
+	"referee/internal/fees"
+	"referee/internal/instrument"
 	"referee/internal/model"
+	"referee/internal/risk"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -26,11 +31,64 @@ func (m *MockRepository) LogPriceTick(ctx context.Context, tick model.PriceTick)
 	return args.Error(0)
 }
 
+func (m *MockRepository) LogBalances(ctx context.Context, balances []model.Balance) error {
+	args := m.Called(ctx, balances)
+	return args.Error(0)
+}
+
+func (m *MockRepository) LogTransfer(ctx context.Context, transfer model.Transfer) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
+
+func (m *MockRepository) LogLedgerEntry(ctx context.Context, entry model.LedgerEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockRepository) LogEquitySnapshot(ctx context.Context, snapshot model.EquitySnapshot) error {
+	args := m.Called(ctx, snapshot)
+	return args.Error(0)
+}
+
+func (m *MockRepository) LogOpportunity(ctx context.Context, opportunity model.Opportunity) error {
+	args := m.Called(ctx, opportunity)
+	return args.Error(0)
+}
+
+func (m *MockRepository) LogEpisode(ctx context.Context, episode model.Episode) error {
+	args := m.Called(ctx, episode)
+	return args.Error(0)
+}
+
+func (m *MockRepository) LogRiskEvent(ctx context.Context, event model.RiskEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
 func (m *MockRepository) Migrate(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
 }
 
+func mustLoadInstruments(t *testing.T, cfg *config.Config) *instrument.Registry {
+	t.Helper()
+	instruments, err := instrument.Load(cfg)
+	if err != nil {
+		t.Fatalf("could not load instruments: %s", err)
+	}
+	return instruments
+}
+
+func mustNewEngine(t *testing.T, logger *slog.Logger, repo *MockRepository, cfg *config.Config, clk clock.Clock) *ArbitrageEngine {
+	t.Helper()
+	engine, err := NewArbitrageEngine(logger, repo, cfg, clk, mustLoadInstruments(t, cfg))
+	if err != nil {
+		t.Fatalf("could not create engine: %s", err)
+	}
+	return engine
+}
+
 func TestArbitrageEngine_ProcessTick(t *testing.T) {
 	mockRepo := new(MockRepository)
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
@@ -40,6 +98,7 @@ func TestArbitrageEngine_ProcessTick(t *testing.T) {
 			SimulatedTradeVolumeEUR: 1000.0,
 			NetworkWithdrawalFeeEUR: 5.0,
 			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR"},
 		},
 		Exchanges: map[string]config.ExchangeConfig{
 			"kraken":  {TakerFeePercent: 0.26},
@@ -47,11 +106,14 @@ func TestArbitrageEngine_ProcessTick(t *testing.T) {
 		},
 	}
 
-	engine := NewArbitrageEngine(logger, mockRepo, cfg)
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
 
 	// Test Case 1: No opportunity
 	t.Run("no opportunity", func(t *testing.T) {
 		mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil).Once()
+		mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
 		tick1 := model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050}
 		engine.ProcessTick(context.Background(), tick1)
 		mockRepo.AssertNotCalled(t, "LogTrade")
@@ -60,11 +122,15 @@ func TestArbitrageEngine_ProcessTick(t *testing.T) {
 	// Test Case 2: Profitable opportunity
 	t.Run("profitable opportunity", func(t *testing.T) {
 		// Create a fresh engine for this test
-		engine2 := NewArbitrageEngine(logger, mockRepo, cfg)
+		engine2 := mustNewEngine(t, logger, mockRepo, cfg, clk)
 
 		// Mock the LogTrade call
-		mockRepo.On("LogTrade", mock.Anything, mock.Anything).Return(nil).Once()
-		mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil).Twice()
+		mockRepo.On("LogTrade", mock.Anything, mock.MatchedBy(func(trade model.SimulatedTrade) bool {
+			return trade.Status == TradeStatusExecuted && trade.DetectedSellPrice == 61000
+		})).Return(nil).Once()
+		mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil).Times(3)
+		mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 		// First, add Kraken price
 		tick1 := model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050}
@@ -73,8 +139,39 @@ func TestArbitrageEngine_ProcessTick(t *testing.T) {
 		// Then add Binance price (should create profitable opportunity)
 		tick2 := model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61050}
 		engine2.ProcessTick(context.Background(), tick2)
+		mockRepo.AssertNotCalled(t, "LogTrade", mock.Anything, mock.Anything)
+
+		clk.Advance(10 * time.Millisecond) // Let the simulated latency elapse
+
+		// The next tick settles the pending execution
+		engine2.ProcessTick(context.Background(), tick1)
+		mockRepo.AssertExpectations(t)
+	})
+
+	// Test Case 2b: Opportunity closes before the latency elapses
+	t.Run("missed opportunity", func(t *testing.T) {
+		mockRepo.Mock = mock.Mock{}
+		engineMissed := mustNewEngine(t, logger, mockRepo, cfg, clk)
+
+		mockRepo.On("LogTrade", mock.Anything, mock.MatchedBy(func(trade model.SimulatedTrade) bool {
+			return trade.Status == TradeStatusMissed &&
+				trade.DetectedSellPrice == 61000 &&
+				trade.SellPrice == 60010 &&
+				trade.NetProfitEUR == 0
+		})).Return(nil).Once()
+		mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil).Times(4)
+		mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+		tick1 := model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050}
+		engineMissed.ProcessTick(context.Background(), tick1)
+		engineMissed.ProcessTick(context.Background(), model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61050})
+
+		// Binance falls back in line before the execution matures
+		engineMissed.ProcessTick(context.Background(), model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 60010, Ask: 60060})
 
-		time.Sleep(20 * time.Millisecond) // Wait for latency simulation
+		clk.Advance(10 * time.Millisecond) // Let the simulated latency elapse
+		engineMissed.ProcessTick(context.Background(), tick1)
 		mockRepo.AssertExpectations(t)
 	})
 
@@ -83,12 +180,1187 @@ func TestArbitrageEngine_ProcessTick(t *testing.T) {
 		// Reset mock for this sub-test
 		mockRepo.Mock = mock.Mock{}
 		mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil).Once()
+		mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
 		mockRepo.AssertNotCalled(t, "LogTrade")
 
-		engine.latestPrices["kraken"] = model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001}
+		engine.market.prices["BTC/EUR"]["kraken"] = model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001}
 		tick3 := model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 60002, Ask: 60003}
 		engine.ProcessTick(context.Background(), tick3)
 
 		mockRepo.AssertNotCalled(t, "LogTrade")
 	})
+
+	// Test Case 3b: Replayed tick timestamps drive the virtual clock
+	t.Run("replayed ticks advance the clock", func(t *testing.T) {
+		mockRepo.Mock = mock.Mock{}
+		replayClock := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		replayEngine := mustNewEngine(t, logger, mockRepo, cfg, replayClock)
+
+		mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil).Times(3)
+		mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockRepo.On("LogTrade", mock.Anything, mock.MatchedBy(func(trade model.SimulatedTrade) bool {
+			return trade.Timestamp.Sub(trade.DetectedAt) == 15*time.Millisecond
+		})).Return(nil).Once()
+
+		start := replayClock.Now()
+		replayEngine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050, ReceivedAt: start.Add(time.Millisecond)})
+		replayEngine.ProcessTick(context.Background(), model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61050, ReceivedAt: start.Add(5 * time.Millisecond)})
+		assert.Equal(t, start.Add(5*time.Millisecond), replayClock.Now())
+
+		// Arriving after the latency, the next tick settles the execution at its own timestamp
+		replayEngine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050, ReceivedAt: start.Add(20 * time.Millisecond)})
+		mockRepo.AssertExpectations(t)
+	})
+
+	// Test Case 4: Profitable at the touch but not after walking the book
+	t.Run("unprofitable after slippage", func(t *testing.T) {
+		mockRepo.Mock = mock.Mock{}
+		mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil).Twice()
+		mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+		engine4 := mustNewEngine(t, logger, mockRepo, cfg, clk)
+		tick1 := model.PriceTick{
+			Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050,
+			Asks: []model.PriceLevel{{Price: 60050, Size: 0.001}, {Price: 61500, Size: 1}},
+		}
+		engine4.ProcessTick(context.Background(), tick1)
+		tick2 := model.PriceTick{
+			Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61050,
+			Bids: []model.PriceLevel{{Price: 61000, Size: 1}},
+		}
+		engine4.ProcessTick(context.Background(), tick2)
+
+		mockRepo.AssertNotCalled(t, "LogTrade", mock.Anything, mock.Anything)
+	})
+}
+
+func TestWalkBook(t *testing.T) {
+	asks := []model.PriceLevel{{Price: 100, Size: 1}, {Price: 110, Size: 1}}
+	bids := []model.PriceLevel{{Price: 120, Size: 0.5}, {Price: 115, Size: 2}}
+
+	buy := buyWithQuote(asks, 100, 155)
+	assert.True(t, buy.Complete)
+	assert.Equal(t, 2, buy.Levels)
+	assert.InDelta(t, 1.5, buy.BaseQty, 1e-9)
+	assert.InDelta(t, 155.0/1.5, buy.VWAP, 1e-9)
+
+	sell := sellBase(bids, 120, buy.BaseQty)
+	assert.True(t, sell.Complete)
+	assert.Equal(t, 2, sell.Levels)
+	assert.InDelta(t, 60+115, sell.QuoteQty, 1e-9)
+
+	assert.False(t, buyWithQuote(asks, 100, 1000).Complete)
+
+	// Ticks without depth fill entirely at the touch
+	touch := buyWithQuote(nil, 100, 1000)
+	assert.True(t, touch.Complete)
+	assert.Equal(t, 100.0, touch.VWAP)
+}
+
+func TestScheduler(t *testing.T) {
+	var s scheduler
+	var order []int
+	start := time.Now()
+
+	s.Schedule(start.Add(20*time.Millisecond), func(ctx context.Context, now time.Time) { order = append(order, 2) }, nil)
+	s.Schedule(start.Add(10*time.Millisecond), func(ctx context.Context, now time.Time) { order = append(order, 1) }, nil)
+	s.Schedule(start.Add(20*time.Millisecond), func(ctx context.Context, now time.Time) { order = append(order, 3) },
+		func(ctx context.Context, now time.Time) { order = append(order, -3) })
+	s.Schedule(start.Add(30*time.Millisecond), func(ctx context.Context, now time.Time) { order = append(order, 4) },
+		func(ctx context.Context, now time.Time) { order = append(order, -4) })
+
+	next, ok := s.Next()
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(10*time.Millisecond), next)
+
+	s.RunDue(context.Background(), start.Add(15*time.Millisecond))
+	assert.Equal(t, []int{1}, order)
+	assert.Equal(t, 3, s.Len())
+
+	s.RunDue(context.Background(), start.Add(20*time.Millisecond))
+	assert.Equal(t, []int{1, 2, 3}, order)
+	assert.Equal(t, 1, s.Len())
+
+	// Cancelled tasks run their cancel function instead
+	s.CancelAll(context.Background(), start.Add(25*time.Millisecond))
+	assert.Equal(t, []int{1, 2, 3, -4}, order)
+	_, ok = s.Next()
+	assert.False(t, ok)
+}
+
+func TestArbitrageEngine_Run(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR"},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26},
+			"binance": {TakerFeePercent: 0.1},
+		},
+	}
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clock.NewReal())
+
+	traded := make(chan model.SimulatedTrade, 1)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		traded <- args.Get(1).(model.SimulatedTrade)
+	}).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	priceChan := make(chan model.PriceTick, 2)
+	done := make(chan error, 1)
+	go func() { done <- engine.Run(ctx, priceChan) }()
+
+	// The execution matures without any further ticks arriving
+	priceChan <- model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050}
+	priceChan <- model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61050}
+
+	select {
+	case trade := <-traded:
+		assert.Equal(t, TradeStatusExecuted, trade.Status)
+	case <-time.After(time.Second):
+		t.Fatal("pending execution was not settled")
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestArbitrageEngine_RunCancelsPending(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR"},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26},
+			"binance": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil)
+	var opportunities []model.Opportunity
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		assert.NoError(t, args.Get(0).(context.Context).Err())
+		opportunities = append(opportunities, args.Get(1).(model.Opportunity))
+	}).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	priceChan := make(chan model.PriceTick)
+	done := make(chan error, 1)
+	go func() { done <- engine.Run(ctx, priceChan) }()
+
+	// The simulated clock never advances, so the execution is still pending at shutdown
+	priceChan <- model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050}
+	priceChan <- model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61050}
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	mockRepo.AssertNotCalled(t, "LogTrade", mock.Anything, mock.Anything)
+	if assert.Len(t, opportunities, 1) {
+		assert.Equal(t, OpportunityCancelled, opportunities[0].Reason)
+		assert.Equal(t, "kraken", opportunities[0].BuyExchange)
+		assert.NotZero(t, opportunities[0].EpisodeID)
+	}
+}
+
+func TestArbitrageEngine_RunTimers(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR"},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26},
+			"binance": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	priceChan := make(chan model.PriceTick)
+	done := make(chan error, 1)
+	go func() { done <- engine.Run(ctx, priceChan) }()
+
+	// However many ticks arrive, only the staleness check and the pending execution wait on the clock
+	priceChan <- model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050}
+	for range 100 {
+		priceChan <- model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61050}
+	}
+	assert.Eventually(t, func() bool { return clk.Waiters() == 2 }, time.Second, time.Millisecond)
+	assert.Never(t, func() bool { return clk.Waiters() > 2 }, 50*time.Millisecond, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestArbitrageEngine_StaleQuotes(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			MaxQuoteAgeMS:           1000,
+			TradingPairs:            []string{"BTC/EUR"},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26, MaxQuoteAgeMS: 500},
+			"binance": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil)
+
+	assert.Equal(t, 500*time.Millisecond, cfg.MaxQuoteAge("kraken"))
+	assert.Equal(t, time.Second, cfg.MaxQuoteAge("binance"))
+
+	// Kraken's feed goes quiet, so its old quote must not be traded against
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050})
+	clk.Advance(600 * time.Millisecond)
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61050})
+	assert.True(t, engine.market.IsStale("BTC/EUR", "kraken"))
+	assert.False(t, engine.market.IsStale("BTC/EUR", "binance"))
+	assert.Equal(t, 0, engine.scheduler.Len())
+
+	// A fresh Kraken quote recovers the exchange and the opportunity is taken
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050})
+	assert.False(t, engine.market.IsStale("BTC/EUR", "kraken"))
+	assert.Equal(t, 1, engine.scheduler.Len())
+}
+
+func TestArbitrageEngine_MultiplePairs(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR", "ETH/EUR"},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26},
+			"binance": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("LogTrade", mock.Anything, mock.MatchedBy(func(trade model.SimulatedTrade) bool {
+		return trade.TradingPair == "ETH/EUR" && trade.Status == TradeStatusExecuted
+	})).Return(nil).Once()
+
+	// Prices of different pairs are never compared with each other
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050})
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "binance", Pair: "ETH/EUR", Bid: 3000, Ask: 3001})
+	assert.Equal(t, 0, engine.scheduler.Len())
+
+	// The same pair across exchanges is
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "ETH/EUR", Bid: 3100, Ask: 3101})
+	assert.Equal(t, 1, engine.scheduler.Len())
+
+	clk.Advance(10 * time.Millisecond)
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60050})
+	mockRepo.AssertExpectations(t)
+}
+
+func TestArbitrageEngine_Triangular(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR", "ETH/EUR", "ETH/BTC"},
+			Triangular:              config.TriangularConfig{Enabled: true},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	var logged model.SimulatedTrade
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		logged = args.Get(1).(model.SimulatedTrade)
+	}).Return(nil).Once()
+
+	// EUR>BTC>ETH>EUR returns 1000 / 60000 / 0.05 * 3100 = 1033.33 EUR before fees
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 59990, Ask: 60000})
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "ETH/BTC", Bid: 0.0499, Ask: 0.05})
+	assert.Equal(t, 0, engine.scheduler.Len())
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "ETH/EUR", Bid: 3100, Ask: 3101})
+	assert.Equal(t, 1, engine.scheduler.Len(), "only one direction of the cycle is profitable")
+
+	clk.Advance(10 * time.Millisecond)
+	engine.executeDue(context.Background(), clk.Now())
+	mockRepo.AssertExpectations(t)
+
+	assert.Equal(t, TradeTypeTriangular, logged.TradeType)
+	assert.Equal(t, "EUR>BTC>ETH>EUR", logged.Route)
+	assert.Equal(t, TradeStatusExecuted, logged.Status)
+	assert.Equal(t, "kraken", logged.BuyExchange)
+	assert.Equal(t, "kraken", logged.SellExchange)
+	assert.InDelta(t, 33.33, logged.GrossProfitEUR, 0.01)
+	// Three taker fees and no withdrawal fee
+	assert.InDelta(t, 30.24, logged.NetProfitEUR, 0.01)
+}
+
+func TestArbitrageEngine_TriangularNonEURStart(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 3000.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR", "ETH/EUR", "ETH/BTC"},
+			Strategies:              []config.StrategyConfig{{Type: "triangular", StartAsset: "BTC"}},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
+	var logged model.SimulatedTrade
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		logged = args.Get(1).(model.SimulatedTrade)
+	}).Return(nil).Once()
+
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 59990, Ask: 60000})
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "ETH/BTC", Bid: 0.0499, Ask: 0.05})
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "ETH/EUR", Bid: 3100, Ask: 3101})
+	assert.Equal(t, 1, engine.scheduler.Len())
+	clk.Advance(10 * time.Millisecond)
+	engine.executeDue(context.Background(), clk.Now())
+	mockRepo.AssertExpectations(t)
+
+	// The cycle spends 3000 EUR worth of BTC at the BTC/EUR mid price
+	assert.Equal(t, "BTC>ETH>EUR>BTC", logged.Route)
+	assert.InDelta(t, 3000/59995.0, logged.VolumeEUR, 1e-9)
+	assert.InDelta(t, 3000/59995.0, logged.Legs[0].QuoteQty, 1e-9)
+}
+
+func TestWalkTriangleLotSize(t *testing.T) {
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			TradingPairs: []string{"BTC/EUR", "ETH/EUR", "ETH/BTC"},
+		},
+		Instruments: config.InstrumentsConfig{
+			Definitions: []config.InstrumentConfig{
+				{Symbol: "BTC/EUR", Exchanges: map[string]config.ListingConfig{"kraken": {LotSize: 0.0001}}},
+				{Symbol: "ETH/BTC", Exchanges: map[string]config.ListingConfig{"kraken": {LotSize: 0.01}}},
+				{Symbol: "ETH/EUR", Exchanges: map[string]config.ListingConfig{"kraken": {LotSize: 0.01}}},
+			},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := newTriangularStrategy(slog.New(slog.DiscardHandler), cfg, mustLoadInstruments(t, cfg), fees.NewModel(cfg, clk), nil,
+		config.StrategyConfig{Type: "triangular"})
+	tri := s.findTriangles("kraken", "EUR", cfg.Arbitrage.TradingPairs)[0]
+	assert.Equal(t, "EUR>BTC>ETH>EUR", tri.Route())
+	ticks := [3]model.PriceTick{
+		{Exchange: "kraken", Pair: "BTC/EUR", Bid: 59990, Ask: 60000},
+		{Exchange: "kraken", Pair: "ETH/BTC", Bid: 0.0499, Ask: 0.05},
+		{Exchange: "kraken", Pair: "ETH/EUR", Bid: 3100, Ask: 3101},
+	}
+
+	fills, amount, ok := s.walkTriangle(tri, ticks, 1000, [3]float64{})
+	assert.True(t, ok)
+	// 1000 EUR buys 0.01666 BTC, of which only whole lots of 0.0001 BTC are bought
+	assert.InDelta(t, 0.0166, fills[0].BaseQty, 1e-12)
+	assert.InDelta(t, 996, fills[0].QuoteQty, 1e-9)
+	// 0.0166 BTC buys 0.332 ETH, of which only whole lots of 0.01 ETH are bought
+	assert.InDelta(t, 0.33, fills[1].BaseQty, 1e-12)
+	assert.InDelta(t, 0.0165, fills[1].QuoteQty, 1e-12)
+	// The EUR and BTC left over are lost: the cycle returns 1023 EUR instead of 1033.33
+	assert.InDelta(t, 1023, fills[2].QuoteQty, 1e-9)
+	assert.InDelta(t, 1023, amount, 1e-9)
+
+	// A buy leg that cannot afford a single lot cannot be filled
+	_, _, ok = s.walkTriangle(tri, ticks, 5, [3]float64{})
+	assert.False(t, ok)
+}
+
+func TestArbitrageEngine_Strategies(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR"},
+			Strategies: []config.StrategyConfig{
+				{Type: "spatial"},
+				{Name: "spatial-large", Type: "spatial", TradeVolumeEUR: 5000},
+				{Name: "spatial-picky", Type: "spatial", MinNetProfitEUR: 100},
+			},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26},
+			"binance": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil)
+
+	logged := make(map[string]model.SimulatedTrade)
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		trade := args.Get(1).(model.SimulatedTrade)
+		logged[trade.Strategy] = trade
+	}).Return(nil)
+
+	// Both spatial strategies with a low profit threshold trade the same crossed books
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001})
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61001})
+	assert.Equal(t, 2, engine.scheduler.Len())
+	clk.Advance(10 * time.Millisecond)
+	engine.executeDue(context.Background(), clk.Now())
+
+	assert.Len(t, logged, 2)
+	assert.InDelta(t, 1000, logged["spatial"].VolumeEUR, 1)
+	assert.Equal(t, "trade_volume_eur=1000 min_net_profit_eur=0", logged["spatial"].StrategyParams)
+	assert.InDelta(t, 5000, logged["spatial-large"].VolumeEUR, 1)
+	assert.Equal(t, "trade_volume_eur=5000 min_net_profit_eur=0", logged["spatial-large"].StrategyParams)
+
+	t.Run("rejects invalid strategies", func(t *testing.T) {
+		duplicate := *cfg
+		duplicate.Arbitrage.Strategies = []config.StrategyConfig{{Type: "spatial"}, {Type: "spatial"}}
+		_, err := NewArbitrageEngine(logger, mockRepo, &duplicate, clk, mustLoadInstruments(t, &duplicate))
+		assert.Error(t, err)
+
+		unknown := *cfg
+		unknown.Arbitrage.Strategies = []config.StrategyConfig{{Type: "momentum"}}
+		_, err = NewArbitrageEngine(logger, mockRepo, &unknown, clk, mustLoadInstruments(t, &unknown))
+		assert.Error(t, err)
+	})
+}
+
+func TestArbitrageEngine_FeeTiers(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR"},
+			// Both trades come from the same episode
+			Episodes: config.EpisodesConfig{MaxExecutions: -1},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken": {TakerFeePercent: 0.26},
+			"binance": {
+				TakerFeePercent: 0.1,
+				FeeSchedule: config.FeeScheduleConfig{
+					Tiers: []config.FeeTierConfig{
+						{MinVolumeEUR: 0, TakerFeePercent: 0.1},
+						{MinVolumeEUR: 500, TakerFeePercent: 0.05},
+					},
+				},
+			},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil)
+
+	var logged []model.SimulatedTrade
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		logged = append(logged, args.Get(1).(model.SimulatedTrade))
+	}).Return(nil)
+
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001})
+	for i := 0; i < 2; i++ {
+		engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61001})
+		clk.Advance(10 * time.Millisecond)
+		engine.executeDue(context.Background(), clk.Now())
+	}
+
+	// The first sale moves Binance into the cheaper tier for the second one
+	assert.Len(t, logged, 2)
+	sold := logged[0].Legs[1].QuoteQty
+	assert.InDelta(t, sold+logged[1].Legs[1].QuoteQty, engine.fees.Volume("binance"), 1e-6)
+	assert.InDelta(t, logged[0].Legs[1].QuoteQty*0.001, logged[0].Legs[1].Fee, 1e-9)
+	assert.InDelta(t, logged[1].Legs[1].QuoteQty*0.0005, logged[1].Legs[1].Fee, 1e-9)
+	assert.InDelta(t, logged[0].TotalFeesEUR-sold*0.0005, logged[1].TotalFeesEUR, 1e-6)
+
+	// Volume older than 30 days no longer counts towards the tier
+	clk.Advance(31 * 24 * time.Hour)
+	assert.Equal(t, 0.0, engine.fees.Volume("binance"))
+}
+
+func TestArbitrageEngine_WithdrawalFees(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR"},
+			// The books stay crossed while prices move, so every trade below is one episode
+			Episodes: config.EpisodesConfig{MaxExecutions: -1},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26, WithdrawalFees: map[string]float64{"btc": 0.0001}},
+			"binance": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil)
+
+	var logged []model.SimulatedTrade
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		logged = append(logged, args.Get(1).(model.SimulatedTrade))
+	}).Return(nil)
+	// trade moves both books and keeps only the trade crossing the final quotes
+	trade := func(kraken, binance model.PriceTick) {
+		for _, tick := range []model.PriceTick{kraken, binance} {
+			logged = nil
+			engine.ProcessTick(context.Background(), tick)
+			clk.Advance(10 * time.Millisecond)
+			engine.executeDue(context.Background(), clk.Now())
+		}
+	}
+
+	// Coins bought on Kraken are withdrawn in BTC, valued at the mid price
+	trade(model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001},
+		model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61001})
+	assert.Len(t, logged, 1)
+	assert.InDelta(t, 0.0001*60500.5, logged[0].WithdrawalFeeEUR, 1e-9)
+	legFees := logged[0].Legs[0].Fee + logged[0].Legs[1].Fee
+	assert.InDelta(t, legFees+logged[0].WithdrawalFeeEUR, logged[0].TotalFeesEUR, 1e-9)
+
+	// The same fee costs more after BTC rallies
+	trade(model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 66000, Ask: 66001},
+		model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 67100, Ask: 67101})
+	assert.Len(t, logged, 1)
+	assert.InDelta(t, 0.0001*66550.5, logged[0].WithdrawalFeeEUR, 1e-9)
+
+	// Exchanges without a configured fee fall back to the flat EUR fee
+	trade(model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 68000, Ask: 68001},
+		model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 66000, Ask: 66001})
+	assert.Len(t, logged, 1)
+	assert.Equal(t, "binance", logged[0].BuyExchange)
+	assert.Equal(t, 5.0, logged[0].WithdrawalFeeEUR)
+}
+
+func TestArbitrageEngine_WithdrawalFeeNonEURQuote(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 3000,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"ETH/BTC", "BTC/EUR"},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26},
+			"binance": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil)
+	var logged []model.SimulatedTrade
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		logged = append(logged, args.Get(1).(model.SimulatedTrade))
+	}).Return(nil)
+	ctx := context.Background()
+
+	// Without a BTC/EUR price neither the volume nor the flat EUR fee can be converted to BTC
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "kraken", Pair: "ETH/BTC", Bid: 0.0499, Ask: 0.05})
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "binance", Pair: "ETH/BTC", Bid: 0.051, Ask: 0.0511})
+	assert.Equal(t, 0, engine.scheduler.Len())
+
+	// The 5 EUR fee is charged as its BTC equivalent
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60000})
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "binance", Pair: "ETH/BTC", Bid: 0.051, Ask: 0.0511})
+	clk.Advance(10 * time.Millisecond)
+	engine.executeDue(ctx, clk.Now())
+	if assert.Len(t, logged, 1) {
+		assert.InDelta(t, 5.0/60000, logged[0].WithdrawalFeeEUR, 1e-12)
+		legFees := logged[0].Legs[0].Fee + logged[0].Legs[1].Fee
+		assert.InDelta(t, legFees+5.0/60000, logged[0].TotalFeesEUR, 1e-12)
+	}
+}
+
+func TestArbitrageEngine_Sizing(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	newConfig := func() *config.Config {
+		return &config.Config{
+			Arbitrage: config.ArbitrageConfig{
+				SimulatedTradeVolumeEUR: 1000.0,
+				NetworkWithdrawalFeeEUR: 5.0,
+				SimulatedLatencyMS:      10,
+				TradingPairs:            []string{"BTC/EUR"},
+				Sizing:                  config.SizingConfig{Enabled: true, MaxTradeVolumeEUR: 3000},
+			},
+			Exchanges: map[string]config.ExchangeConfig{
+				"kraken":  {TakerFeePercent: 0.26, Balances: map[string]float64{"eur": 800}},
+				"binance": {TakerFeePercent: 0.1, Balances: map[string]float64{"btc": 1}},
+			},
+		}
+	}
+	// Only the first 0.02 BTC on Kraken are cheap enough to sell on Binance at a profit
+	kraken := model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 59990, Ask: 60000,
+		Asks: []model.PriceLevel{{Price: 60000, Size: 0.02}, {Price: 61000, Size: 1}}}
+	binance := model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61010,
+		Bids: []model.PriceLevel{{Price: 61000, Size: 1}}}
+	run := func(t *testing.T, cfg *config.Config) (*ArbitrageEngine, model.SimulatedTrade) {
+		mockRepo := new(MockRepository)
+		clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+		mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("LogBalances", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("LogLedgerEntry", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("LogEquitySnapshot", mock.Anything, mock.Anything).Return(nil)
+		var logged model.SimulatedTrade
+		mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			logged = args.Get(1).(model.SimulatedTrade)
+		}).Return(nil).Once()
+
+		engine.ProcessTick(context.Background(), kraken)
+		engine.ProcessTick(context.Background(), binance)
+		clk.Advance(10 * time.Millisecond)
+		engine.executeDue(context.Background(), clk.Now())
+		return engine, logged
+	}
+
+	t.Run("maximizes net profit", func(t *testing.T) {
+		_, logged := run(t, newConfig())
+
+		// Buying exactly the cheap level beats the fixed 1000 EUR, which leaves profit on the table
+		assert.Equal(t, TradeStatusExecuted, logged.Status)
+		assert.InDelta(t, 1200, logged.VolumeEUR, 0.1)
+		assert.InDelta(t, 20-1200*0.0026-1220*0.001-5, logged.NetProfitEUR, 0.01)
+		assert.Equal(t, "trade_volume_eur=1000 min_net_profit_eur=0 max_trade_volume_eur=3000", logged.StrategyParams)
+
+		assert.Len(t, logged.ProfitCurve, 10)
+		for _, sample := range logged.ProfitCurve {
+			assert.LessOrEqual(t, sample.NetProfitEUR, logged.NetProfitEUR+1e-9)
+		}
+		assert.Equal(t, 1200.0, logged.ProfitCurve[3].VolumeEUR)
+		assert.Greater(t, logged.ProfitCurve[3].NetProfitEUR, logged.ProfitCurve[2].NetProfitEUR)
+		assert.Greater(t, logged.ProfitCurve[3].NetProfitEUR, logged.ProfitCurve[4].NetProfitEUR)
+	})
+
+	t.Run("bounded by inventory", func(t *testing.T) {
+		cfg := newConfig()
+		cfg.Arbitrage.Inventory.Enabled = true
+		cfg.Arbitrage.NetworkWithdrawalFeeEUR = 0
+		engine, logged := run(t, cfg)
+
+		// Kraken's 800 EUR must cover the volume and the taker fee
+		assert.Equal(t, TradeStatusExecuted, logged.Status)
+		assert.InDelta(t, 800/1.0026, logged.VolumeEUR, 0.01)
+		assert.InDelta(t, 0, engine.inventory.Balance("kraken", "EUR"), 0.01)
+	})
+}
+
+func TestArbitrageEngine_Opportunities(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			SimulatedLatencyMS:      10,
+			MaxQuoteAgeMS:           1000,
+			TradingPairs:            []string{"BTC/EUR"},
+			Inventory:               config.InventoryConfig{Enabled: true},
+			Episodes:                config.EpisodesConfig{MaxExecutions: -1},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26, Balances: map[string]float64{"eur": 1500}},
+			"binance": {TakerFeePercent: 0.1, Balances: map[string]float64{"btc": 0.02}},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogBalances", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogLedgerEntry", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEquitySnapshot", mock.Anything, mock.Anything).Return(nil)
+
+	var logged []model.Opportunity
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		logged = append(logged, args.Get(1).(model.Opportunity))
+	}).Return(nil)
+	ctx := context.Background()
+
+	// The books cross, but not by enough to pay the fees
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001})
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 60050, Ask: 60060})
+
+	// A wider spread is executed once the latency has elapsed
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61001})
+	clk.Advance(10 * time.Millisecond)
+	engine.executeDue(ctx, clk.Now())
+
+	// Kraken's remaining EUR cannot pay for another trade
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61001})
+
+	// Kraken's feed goes quiet
+	clk.Advance(1100 * time.Millisecond)
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61001})
+
+	reasons := make([]string, 0, len(logged))
+	for _, opportunity := range logged {
+		reasons = append(reasons, opportunity.Reason)
+	}
+	assert.Equal(t, []string{OpportunityUnprofitable, OpportunityExecuted, OpportunityInventoryBlocked, OpportunityStale}, reasons)
+
+	unprofitable := logged[0]
+	assert.Equal(t, "spatial", unprofitable.Strategy)
+	assert.Equal(t, "kraken", unprofitable.BuyExchange)
+	assert.Greater(t, unprofitable.GrossProfitEUR, 0.0)
+	assert.Less(t, unprofitable.NetProfitEUR, 0.0)
+	assert.InDelta(t, unprofitable.GrossProfitEUR-unprofitable.TotalFeesEUR, unprofitable.NetProfitEUR, 1e-9)
+	assert.Equal(t, clk.Now().Add(-1110*time.Millisecond), unprofitable.Timestamp)
+}
+
+func TestArbitrageEngine_Episodes(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	newEngine := func(t *testing.T, maxExecutions int) (*ArbitrageEngine, *clock.Simulated, *[]model.SimulatedTrade, *[]model.Episode) {
+		mockRepo := new(MockRepository)
+		cfg := &config.Config{
+			Arbitrage: config.ArbitrageConfig{
+				SimulatedTradeVolumeEUR: 1000.0,
+				NetworkWithdrawalFeeEUR: 5.0,
+				SimulatedLatencyMS:      10,
+				TradingPairs:            []string{"BTC/EUR"},
+				Episodes:                config.EpisodesConfig{MaxExecutions: maxExecutions},
+			},
+			Exchanges: map[string]config.ExchangeConfig{
+				"kraken":  {TakerFeePercent: 0.26},
+				"binance": {TakerFeePercent: 0.1},
+			},
+		}
+		clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+		mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+
+		var trades []model.SimulatedTrade
+		mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			trades = append(trades, args.Get(1).(model.SimulatedTrade))
+		}).Return(nil)
+		var episodes []model.Episode
+		mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			episodes = append(episodes, args.Get(1).(model.Episode))
+		}).Return(nil)
+		return engine, clk, &trades, &episodes
+	}
+	kraken := model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001}
+	binance := func(bid float64) model.PriceTick {
+		return model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: bid, Ask: bid + 1}
+	}
+
+	t.Run("a persistent spread is traded once", func(t *testing.T) {
+		engine, clk, trades, episodes := newEngine(t, 0)
+		ctx := context.Background()
+
+		engine.ProcessTick(ctx, kraken)
+		for _, bid := range []float64{61000, 61200, 61100} {
+			engine.ProcessTick(ctx, binance(bid))
+			clk.Advance(100 * time.Millisecond)
+		}
+		engine.executeDue(ctx, clk.Now())
+		assert.Len(t, *trades, 1)
+		assert.Empty(t, *episodes)
+
+		// Binance falls back in line and the episode ends
+		engine.ProcessTick(ctx, binance(60000))
+		if assert.Len(t, *episodes, 1) {
+			ep := (*episodes)[0]
+			assert.Equal(t, (*trades)[0].EpisodeID, ep.ID)
+			assert.Equal(t, "kraken", ep.BuyExchange)
+			assert.Equal(t, 3, ep.Ticks)
+			assert.Equal(t, 1, ep.Executions)
+			assert.Equal(t, 300*time.Millisecond, ep.Duration())
+			assert.InDelta(t, (61200.0-60001)/60001*100, ep.PeakSpreadPercent, 1e-9)
+		}
+
+		// The books crossing again starts a new episode
+		engine.ProcessTick(ctx, binance(61000))
+		clk.Advance(10 * time.Millisecond)
+		engine.executeDue(ctx, clk.Now())
+		assert.Len(t, *trades, 2)
+		assert.Equal(t, (*episodes)[0].ID+1, (*trades)[1].EpisodeID)
+	})
+
+	t.Run("configurable executions per episode", func(t *testing.T) {
+		engine, clk, trades, _ := newEngine(t, 2)
+		ctx := context.Background()
+
+		engine.ProcessTick(ctx, kraken)
+		for _, bid := range []float64{61000, 61200, 61100} {
+			engine.ProcessTick(ctx, binance(bid))
+			clk.Advance(100 * time.Millisecond)
+		}
+		engine.executeDue(ctx, clk.Now())
+		assert.Len(t, *trades, 2)
+	})
+}
+
+func TestArbitrageEngine_Risk(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR"},
+			Episodes:                config.EpisodesConfig{MaxExecutions: -1},
+			Risk:                    config.RiskConfig{MaxTradesPerMinute: 1},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26},
+			"binance": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil)
+
+	var trades []model.SimulatedTrade
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		trades = append(trades, args.Get(1).(model.SimulatedTrade))
+	}).Return(nil)
+	var reasons []string
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		reasons = append(reasons, args.Get(1).(model.Opportunity).Reason)
+	}).Return(nil)
+	var events []model.RiskEvent
+	mockRepo.On("LogRiskEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(model.RiskEvent))
+	}).Return(nil)
+	ctx := context.Background()
+	binance := model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 61000, Ask: 61001}
+
+	// Only one trade may be submitted per minute; executions are recorded once they settle
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001})
+	engine.ProcessTick(ctx, binance)
+	engine.ProcessTick(ctx, binance)
+	clk.Advance(10 * time.Millisecond)
+	engine.executeDue(ctx, clk.Now())
+	assert.Len(t, trades, 1)
+
+	// The kill switch blocks trading once the rate limit has passed, but ticks are still recorded
+	engine.Halt(ctx, "maintenance")
+	engine.Halt(ctx, "maintenance")
+	clk.Advance(time.Minute)
+	engine.ProcessTick(ctx, binance)
+	mockRepo.AssertNumberOfCalls(t, "LogPriceTick", 4)
+
+	engine.Resume(ctx)
+	engine.ProcessTick(ctx, binance)
+	clk.Advance(10 * time.Millisecond)
+	engine.executeDue(ctx, clk.Now())
+	assert.Len(t, trades, 2)
+
+	assert.Equal(t, []string{OpportunityRiskBlocked, OpportunityExecuted, OpportunityRiskBlocked, OpportunityExecuted}, reasons)
+	if assert.Len(t, events, 4) {
+		assert.Equal(t, risk.RuleTradeRate, events[0].Rule)
+		assert.Equal(t, RiskActionBlocked, events[0].Action)
+		assert.Equal(t, "binance", events[0].SellExchange)
+		assert.Equal(t, model.RiskEvent{Timestamp: events[1].Timestamp, Rule: risk.RuleKillSwitch, Action: RiskActionHalted, Detail: "maintenance"}, events[1])
+		assert.Equal(t, risk.RuleKillSwitch, events[2].Rule)
+		assert.Equal(t, RiskActionBlocked, events[2].Action)
+		assert.Equal(t, RiskActionResumed, events[3].Action)
+	}
+}
+
+func TestArbitrageEngine_RiskInEUR(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 3000,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"ETH/BTC", "BTC/EUR"},
+			Risk:                    config.RiskConfig{MaxExposureEUR: 2000},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26},
+			"binance": {TakerFeePercent: 0.1},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	var opportunities []model.Opportunity
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		opportunities = append(opportunities, args.Get(1).(model.Opportunity))
+	}).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
+	var events []model.RiskEvent
+	mockRepo.On("LogRiskEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		events = append(events, args.Get(1).(model.RiskEvent))
+	}).Return(nil)
+	ctx := context.Background()
+
+	// Without a BTC/EUR price the EUR volume cannot be sized in BTC
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "kraken", Pair: "ETH/BTC", Bid: 0.0499, Ask: 0.05})
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "binance", Pair: "ETH/BTC", Bid: 0.051, Ask: 0.0511})
+	assert.Equal(t, 0, engine.scheduler.Len())
+	if assert.Len(t, opportunities, 1) {
+		assert.Equal(t, OpportunityUnpriced, opportunities[0].Reason)
+	}
+
+	// The 3000 EUR volume is 0.05 BTC, and is checked against the limit in EUR
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60000})
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "binance", Pair: "ETH/BTC", Bid: 0.051, Ask: 0.0511})
+	assert.Equal(t, 0, engine.scheduler.Len())
+	if assert.Len(t, events, 1) {
+		assert.Contains(t, events[0].Detail, "3000.00 EUR")
+	}
+}
+
+func TestArbitrageEngine_Inventory(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			NetworkWithdrawalFeeEUR: 5.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR"},
+			Inventory:               config.InventoryConfig{Enabled: true},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26, Balances: map[string]float64{"eur": 1500}},
+			"binance": {TakerFeePercent: 0.1, Balances: map[string]float64{"btc": 0.02}},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	var logged model.SimulatedTrade
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		logged = args.Get(1).(model.SimulatedTrade)
+	}).Return(nil).Once()
+	mockRepo.On("LogBalances", mock.Anything, mock.MatchedBy(func(balances []model.Balance) bool {
+		return len(balances) == 4
+	})).Return(nil).Once()
+	mockRepo.On("LogLedgerEntry", mock.Anything, mock.MatchedBy(func(entry model.LedgerEntry) bool {
+		return entry.Kind == "trade" && len(entry.Postings) == 12
+	})).Return(nil).Once()
+	var snapshot model.EquitySnapshot
+	mockRepo.On("LogEquitySnapshot", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		snapshot = args.Get(1).(model.EquitySnapshot)
+	}).Return(nil)
+
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001})
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 60300, Ask: 60301})
+	assert.Equal(t, 1, engine.scheduler.Len())
+	clk.Advance(10 * time.Millisecond)
+	engine.executeDue(context.Background(), clk.Now())
+	mockRepo.AssertExpectations(t)
+
+	// Pre-funded inventory means no withdrawal fee, only the two taker fees
+	assert.Equal(t, TradeStatusExecuted, logged.Status)
+	assert.InDelta(t, 1000*(0.0026+0.001*60300/60001), logged.TotalFeesEUR, 0.01)
+	assert.InDelta(t, 1500-1000-2.6, engine.inventory.Balance("kraken", "EUR"), 0.01)
+	assert.InDelta(t, 1000.0/60001, engine.inventory.Balance("kraken", "BTC"), 1e-9)
+
+	// Kraken no longer holds enough EUR to buy another 1000 EUR of BTC
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 60301, Ask: 60302})
+	assert.Equal(t, 0, engine.scheduler.Len())
+
+	// The ledger marks the inventory to market once the snapshot interval has elapsed
+	clk.Advance(time.Minute)
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001})
+	assert.InDelta(t, logged.NetProfitEUR, snapshot.RealizedPnLEUR, 1e-9)
+	// The trade bought and sold the same amount of BTC, so nothing is left unrealized
+	mid := (60000.5 + 60301.5) / 2
+	assert.InDelta(t, 1500+0.02*mid+logged.NetProfitEUR, snapshot.EquityEUR, 1e-6)
+	assert.InDelta(t, 0, snapshot.UnrealizedPnLEUR, 1e-6)
+}
+
+func TestArbitrageEngine_NonEURQuote(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			// 0.05 BTC at the BTC/EUR mid price
+			SimulatedTradeVolumeEUR: 0.05 * 60000.5,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"ETH/BTC", "BTC/EUR", "ETH/EUR"},
+			Inventory:               config.InventoryConfig{Enabled: true},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26, Balances: map[string]float64{"BTC": 1}},
+			"binance": {TakerFeePercent: 0.1, Balances: map[string]float64{"ETH": 2}},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("LogBalances", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogLedgerEntry", mock.Anything, mock.Anything).Return(nil)
+
+	var logged model.SimulatedTrade
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		logged = args.Get(1).(model.SimulatedTrade)
+	}).Return(nil).Once()
+	var snapshot model.EquitySnapshot
+	mockRepo.On("LogEquitySnapshot", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		snapshot = args.Get(1).(model.EquitySnapshot)
+	}).Return(nil)
+
+	// ETH/BTC is quoted in BTC, so the trade's profit is an amount of BTC
+	ctx := context.Background()
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001})
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "kraken", Pair: "ETH/EUR", Bid: 3000, Ask: 3001})
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "kraken", Pair: "ETH/BTC", Bid: 0.0499, Ask: 0.05})
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "binance", Pair: "ETH/BTC", Bid: 0.051, Ask: 0.0511})
+	clk.Advance(10 * time.Millisecond)
+	engine.executeDue(ctx, clk.Now())
+	assert.Equal(t, TradeStatusExecuted, logged.Status)
+	assert.InDelta(t, 0.051-0.05-0.05*0.0026-0.051*0.001, logged.NetProfitEUR, 1e-9)
+
+	// Realized PnL is booked at the BTC/EUR mid price
+	clk.Advance(time.Minute)
+	engine.ProcessTick(ctx, model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001})
+	assert.InDelta(t, logged.NetProfitEUR*60000.5, snapshot.RealizedPnLEUR, 1e-6)
+}
+
+func TestArbitrageEngine_Rebalancing(t *testing.T) {
+	mockRepo := new(MockRepository)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{
+			SimulatedTradeVolumeEUR: 1000.0,
+			SimulatedLatencyMS:      10,
+			TradingPairs:            []string{"BTC/EUR"},
+			Risk:                    config.RiskConfig{MaxExposureEUR: 1500},
+			Inventory: config.InventoryConfig{
+				Enabled: true,
+				Rebalance: config.RebalanceConfig{
+					Enabled:       true,
+					SkewThreshold: 0.6,
+					Transfers: map[string]config.TransferConfig{
+						"btc": {Fee: 0.0001, DelayMS: 30 * 60 * 1000},
+					},
+				},
+			},
+		},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {TakerFeePercent: 0.26, Balances: map[string]float64{"EUR": 1500, "BTC": 0}},
+			"binance": {TakerFeePercent: 0.1, Balances: map[string]float64{"EUR": 0, "BTC": 0.02}},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	engine := mustNewEngine(t, logger, mockRepo, cfg, clk)
+	mockRepo.On("LogPriceTick", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogOpportunity", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEpisode", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mockRepo.On("LogTrade", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogBalances", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogLedgerEntry", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("LogEquitySnapshot", mock.Anything, mock.Anything).Return(nil)
+
+	var transfer model.Transfer
+	mockRepo.On("LogTransfer", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		transfer = args.Get(1).(model.Transfer)
+	}).Return(nil).Once()
+
+	// Buying on Kraken and selling on Binance leaves Kraken holding most of the BTC
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "kraken", Pair: "BTC/EUR", Bid: 60000, Ask: 60001})
+	engine.ProcessTick(context.Background(), model.PriceTick{Exchange: "binance", Pair: "BTC/EUR", Bid: 60300, Ask: 60301})
+	clk.Advance(10 * time.Millisecond)
+	engine.executeDue(context.Background(), clk.Now())
+	mockRepo.AssertExpectations(t)
+
+	btc := 1000.0 / 60001
+	assert.Equal(t, "BTC", transfer.Asset)
+	assert.Equal(t, "kraken", transfer.FromExchange)
+	assert.Equal(t, "binance", transfer.ToExchange)
+	assert.InDelta(t, btc-0.01, transfer.Amount, 1e-9)
+	assert.InDelta(t, 0.0001*(60000+60001+60300+60301)/4, transfer.FeeEUR, 1e-6)
+	assert.InDelta(t, 0.01, engine.inventory.Balance("kraken", "BTC"), 1e-9)
+	assert.InDelta(t, transfer.Amount, engine.inventory.InTransit("BTC"), 1e-9)
+
+	// The BTC moved off Kraken releases part of its exposure; Binance keeps the trade's
+	next := func(buy, sell string) model.SimulatedTrade {
+		return model.SimulatedTrade{BuyExchange: buy, SellExchange: sell, VolumeEUR: 800}
+	}
+	_, _, ok := engine.risk.Check(clk.Now(), next("kraken", "bitstamp"), 800, true)
+	assert.True(t, ok)
+	rule, _, ok := engine.risk.Check(clk.Now(), next("binance", "bitstamp"), 800, true)
+	assert.False(t, ok)
+	assert.Equal(t, risk.RuleExposure, rule)
+
+	// The funds only become available once the transfer has been confirmed
+	clk.Advance(30 * time.Minute)
+	engine.executeDue(context.Background(), clk.Now())
+	assert.Equal(t, 0.0, engine.inventory.InTransit("BTC"))
+	assert.InDelta(t, 0.02-btc+transfer.Amount-0.0001, engine.inventory.Balance("binance", "BTC"), 1e-9)
 }
@@ -0,0 +1,102 @@
+package arbitrage
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"referee/internal/model"
+)
+
+// episode is an opportunity that persists across ticks. It stays open for as long as
+// its strategy keeps detecting it, and ends on the first tick of one of its quotes that
+// no longer produces it.
+type episode struct {
+	model.Episode
+	key    string
+	quotes []quoteKey
+}
+
+// episodeTracker groups the opportunities detected on every tick into episodes and
+// limits how many trades each episode may submit. Episode IDs are only unique within
+// the process; the repository scopes them to the run.
+type episodeTracker struct {
+	maxExecutions int // Negative means unlimited; the engine replaces zero with the default of 1
+	nextID        int64
+	open          map[string]*episode
+}
+
+func newEpisodeTracker(maxExecutions int) *episodeTracker {
+	return &episodeTracker{maxExecutions: maxExecutions, nextID: 1, open: make(map[string]*episode)}
+}
+
+// episodeKey identifies the opportunities of one strategy that trade the same thing.
+func episodeKey(strategy string, trade model.SimulatedTrade) string {
+	return strings.Join([]string{strategy, trade.TradeType, trade.TradingPair, trade.Route, trade.BuyExchange, trade.SellExchange}, "|")
+}
+
+// observe adds an opportunity detected at now to its episode, starting a new episode
+// if none is open.
+func (t *episodeTracker) observe(now time.Time, strategy string, opportunity Opportunity) *episode {
+	trade := opportunity.Trade
+	key := episodeKey(strategy, trade)
+	ep, ok := t.open[key]
+	if !ok {
+		ep = &episode{
+			Episode: model.Episode{
+				ID:               t.nextID,
+				Strategy:         strategy,
+				TradeType:        trade.TradeType,
+				TradingPair:      trade.TradingPair,
+				Route:            trade.Route,
+				BuyExchange:      trade.BuyExchange,
+				SellExchange:     trade.SellExchange,
+				StartedAt:        now,
+				PeakNetProfitEUR: trade.NetProfitEUR,
+			},
+			key:    key,
+			quotes: opportunity.Quotes,
+		}
+		t.nextID++
+		t.open[key] = ep
+	}
+	ep.Ticks++
+	ep.EndedAt = now
+	ep.PeakSpreadPercent = max(ep.PeakSpreadPercent, trade.SpreadPercent())
+	ep.PeakNetProfitEUR = max(ep.PeakNetProfitEUR, trade.NetProfitEUR)
+	return ep
+}
+
+// allows reports whether the episode may submit another trade.
+func (t *episodeTracker) allows(ep *episode) bool {
+	return t.maxExecutions < 0 || ep.Executions < t.maxExecutions
+}
+
+// end closes the open episodes that depend on the quote a tick just updated but were
+// not detected on that tick, i.e. whose books have uncrossed. seen holds the keys of
+// the episodes detected on the tick.
+func (t *episodeTracker) end(now time.Time, updated quoteKey, seen map[string]bool) []model.Episode {
+	var ended []model.Episode
+	for key, ep := range t.open {
+		if seen[key] || !slices.Contains(ep.quotes, updated) {
+			continue
+		}
+		ep.EndedAt = now
+		ended = append(ended, ep.Episode)
+		delete(t.open, key)
+	}
+	slices.SortFunc(ended, func(a, b model.Episode) int { return int(a.ID - b.ID) })
+	return ended
+}
+
+// endAll closes every open episode, e.g. on shutdown. Their end is the last tick they
+// were detected on.
+func (t *episodeTracker) endAll() []model.Episode {
+	ended := make([]model.Episode, 0, len(t.open))
+	for key, ep := range t.open {
+		ended = append(ended, ep.Episode)
+		delete(t.open, key)
+	}
+	slices.SortFunc(ended, func(a, b model.Episode) int { return int(a.ID - b.ID) })
+	return ended
+}
@@ -0,0 +1,87 @@
+package arbitrage
+
+import (
+	"math"
+
+	"referee/internal/model"
+)
+
+// fill describes the outcome of walking one side of an order book.
+type fill struct {
+	BaseQty  float64 // Amount of crypto bought or sold
+	QuoteQty float64 // Amount of EUR spent or received
+	VWAP     float64 // Volume-weighted average fill price
+	Levels   int     // Number of price levels consumed
+	Complete bool    // False if the book ran out of liquidity
+}
+
+// bookSide returns the depth levels to walk, falling back to unlimited liquidity
+// at the top of book for ticks that carry no depth.
+func bookSide(levels []model.PriceLevel, best float64) []model.PriceLevel {
+	if len(levels) == 0 {
+		return []model.PriceLevel{{Price: best, Size: math.Inf(1)}}
+	}
+	return levels
+}
+
+// buyWithQuote walks the asks, spending quoteAmount EUR.
+func buyWithQuote(asks []model.PriceLevel, bestAsk, quoteAmount float64) fill {
+	var f fill
+	remaining := quoteAmount
+	for _, level := range bookSide(asks, bestAsk) {
+		if remaining <= 0 {
+			break
+		}
+		spend := math.Min(remaining, level.Price*level.Size)
+		f.BaseQty += spend / level.Price
+		f.QuoteQty += spend
+		f.Levels++
+		remaining -= spend
+	}
+	return f.finish(remaining <= 0)
+}
+
+// buyBase walks the asks, buying baseAmount of crypto.
+func buyBase(asks []model.PriceLevel, bestAsk, baseAmount float64) fill {
+	return walkBase(bookSide(asks, bestAsk), baseAmount)
+}
+
+// sellBase walks the bids, selling baseAmount of crypto.
+func sellBase(bids []model.PriceLevel, bestBid, baseAmount float64) fill {
+	return walkBase(bookSide(bids, bestBid), baseAmount)
+}
+
+// walkBase consumes levels best first until baseAmount of crypto has been filled.
+func walkBase(levels []model.PriceLevel, baseAmount float64) fill {
+	var f fill
+	remaining := baseAmount
+	for _, level := range levels {
+		if remaining <= 0 {
+			break
+		}
+		qty := math.Min(remaining, level.Size)
+		f.BaseQty += qty
+		f.QuoteQty += qty * level.Price
+		f.Levels++
+		remaining -= qty
+	}
+	return f.finish(remaining <= 0)
+}
+
+func (f fill) finish(complete bool) fill {
+	f.Complete = complete
+	if f.BaseQty > 0 {
+		f.VWAP = f.QuoteQty / f.BaseQty
+	}
+	return f
+}
+
+// roundToLot rounds a quantity down to a whole number of lots. A non-positive
+// lot size leaves the quantity unchanged.
+func roundToLot(qty, lotSize float64) float64 {
+	if lotSize <= 0 {
+		return qty
+	}
+	// The epsilon absorbs representation error for quantities already on a lot boundary
+	return math.Floor(qty/lotSize+1e-9) * lotSize
+}
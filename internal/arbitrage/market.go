@@ -0,0 +1,87 @@
+package arbitrage
+
+import (
+	"time"
+
+	"referee/internal/model"
+)
+
+// quoteKey identifies the quote stream of one pair on one exchange.
+type quoteKey struct {
+	Pair     string
+	Exchange string
+}
+
+// Market is the engine's view of the latest quotes, shared by every strategy.
+type Market struct {
+	prices     map[string]map[string]model.PriceTick // pair -> exchange -> latest tick
+	staleSince map[quoteKey]time.Time
+}
+
+func newMarket() *Market {
+	return &Market{
+		prices:     make(map[string]map[string]model.PriceTick),
+		staleSince: make(map[quoteKey]time.Time),
+	}
+}
+
+// update records tick as the latest quote of its pair on its exchange.
+func (m *Market) update(tick model.PriceTick) {
+	pairPrices, ok := m.prices[tick.Pair]
+	if !ok {
+		pairPrices = make(map[string]model.PriceTick)
+		m.prices[tick.Pair] = pairPrices
+	}
+	pairPrices[tick.Exchange] = tick
+}
+
+// Quote returns the latest quote of the pair on the exchange. The second return value
+// is false if there is no quote yet or it has gone stale.
+func (m *Market) Quote(pair, exchange string) (model.PriceTick, bool) {
+	tick, ok := m.prices[pair][exchange]
+	return tick, ok && !m.IsStale(pair, exchange)
+}
+
+// Quotes returns the latest quote of the pair on every exchange, stale or not.
+func (m *Market) Quotes(pair string) map[string]model.PriceTick {
+	return m.prices[pair]
+}
+
+// IsStale reports whether the pair's quote on the exchange was stale at the last staleness check.
+func (m *Market) IsStale(pair, exchange string) bool {
+	_, stale := m.staleSince[quoteKey{Pair: pair, Exchange: exchange}]
+	return stale
+}
+
+// Mid returns the average mid price of the pair across exchanges with a fresh quote.
+func (m *Market) Mid(pair string) (float64, bool) {
+	var sum float64
+	var count int
+	for exchange, tick := range m.prices[pair] {
+		if m.IsStale(pair, exchange) || tick.Bid <= 0 || tick.Ask <= 0 {
+			continue
+		}
+		sum += (tick.Bid + tick.Ask) / 2
+		count++
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
+
+// ValueIn converts amount of asset into quote at the current mid price, using either
+// the ASSET/QUOTE or the QUOTE/ASSET pair. The second return value is false if neither
+// pair has a fresh quote.
+func (m *Market) ValueIn(quote, asset string, amount float64) (float64, bool) {
+	if asset == quote {
+		return amount, true
+	}
+	if mid, ok := m.Mid(asset + "/" + quote); ok {
+		return amount * mid, true
+	}
+	if mid, ok := m.Mid(quote + "/" + asset); ok {
+		return amount / mid, true
+	}
+	return 0, false
+}
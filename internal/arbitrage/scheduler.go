@@ -0,0 +1,81 @@
+package arbitrage
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// scheduledTask is a unit of work due at a point in time. cancel, if set, is invoked
+// instead of run when the task is cancelled before it is due.
+type scheduledTask struct {
+	due    time.Time
+	seq    uint64
+	run    func(ctx context.Context, now time.Time)
+	cancel func(ctx context.Context, now time.Time)
+}
+
+// taskHeap orders tasks by due time, then by submission order.
+type taskHeap []*scheduledTask
+
+func (h taskHeap) Len() int { return len(h) }
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].due.Equal(h[j].due) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].due.Before(h[j].due)
+}
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *taskHeap) Push(x any)   { *h = append(*h, x.(*scheduledTask)) }
+func (h *taskHeap) Pop() any {
+	old := *h
+	task := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return task
+}
+
+// scheduler holds pending simulated executions so the engine can keep consuming
+// ticks while they mature. It is not safe for concurrent use; the engine goroutine owns it.
+type scheduler struct {
+	tasks taskHeap
+	seq   uint64
+}
+
+// Schedule registers run to be invoked once the clock reaches due, or cancel if the
+// task is cancelled first. cancel may be nil.
+func (s *scheduler) Schedule(due time.Time, run, cancel func(ctx context.Context, now time.Time)) {
+	s.seq++
+	heap.Push(&s.tasks, &scheduledTask{due: due, seq: s.seq, run: run, cancel: cancel})
+}
+
+// Next returns the due time of the earliest pending task.
+func (s *scheduler) Next() (time.Time, bool) {
+	if len(s.tasks) == 0 {
+		return time.Time{}, false
+	}
+	return s.tasks[0].due, true
+}
+
+// Len returns the number of pending tasks.
+func (s *scheduler) Len() int {
+	return len(s.tasks)
+}
+
+// RunDue runs, in due order, every task due at or before now.
+func (s *scheduler) RunDue(ctx context.Context, now time.Time) {
+	for len(s.tasks) > 0 && !s.tasks[0].due.After(now) {
+		task := heap.Pop(&s.tasks).(*scheduledTask)
+		task.run(ctx, now)
+	}
+}
+
+// CancelAll removes every pending task and, in due order, invokes the cancel function
+// of those that have one.
+func (s *scheduler) CancelAll(ctx context.Context, now time.Time) {
+	for len(s.tasks) > 0 {
+		task := heap.Pop(&s.tasks).(*scheduledTask)
+		if task.cancel != nil {
+			task.cancel(ctx, now)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package arbitrage
+
+import (
+	"fmt"
+	"math"
+
+	"referee/internal/config"
+	"referee/internal/model"
+)
+
+const (
+	// defaultSizingSamples is the number of volumes evaluated when none is configured.
+	defaultSizingSamples = 10
+	// sizingRefinements is the number of golden-section steps taken around the best sample.
+	sizingRefinements = 20
+)
+
+// invPhi is the reciprocal of the golden ratio, by which each refinement shrinks the interval.
+var invPhi = (math.Sqrt(5) - 1) / 2
+
+// sizer chooses the volume of a trade. When it is disabled, every trade uses the
+// strategy's fixed volume.
+type sizer struct {
+	enabled   bool
+	maxVolume float64
+	samples   int
+}
+
+func newSizer(cfg config.SizingConfig, strategyCfg config.StrategyConfig) sizer {
+	z := sizer{enabled: cfg.Enabled, maxVolume: strategyCfg.MaxTradeVolumeEUR, samples: cfg.Samples}
+	if z.maxVolume == 0 {
+		z.maxVolume = cfg.MaxTradeVolumeEUR
+	}
+	if z.maxVolume == 0 {
+		z.maxVolume = strategyCfg.TradeVolumeEUR
+	}
+	if z.samples <= 0 {
+		z.samples = defaultSizingSamples
+	}
+	return z
+}
+
+// params describes the sizing parameters, to be appended to Strategy.Params.
+func (z sizer) params() string {
+	if !z.enabled {
+		return ""
+	}
+	return fmt.Sprintf(" max_trade_volume_eur=%g", z.maxVolume)
+}
+
+// optimize finds the volume up to limit with the highest net profit. It evaluates
+// volumes spread evenly across the range, then narrows in on the best one with a
+// golden-section search between its neighbours. While the books have depth, net profit
+// is concave in the volume: fees grow linearly and every extra unit fills at a worse
+// price. Volumes the books or listings cannot fill are skipped. The returned trade
+// carries the evenly spread samples as its profit curve, and the second return value
+// is the chosen volume. If no volume can be filled, the last evaluation is returned
+// with false.
+func (z sizer) optimize(limit float64, evaluate func(volume float64) (model.SimulatedTrade, bool)) (model.SimulatedTrade, float64, bool) {
+	var best, last model.SimulatedTrade
+	var bestVolume float64
+	found := false
+	try := func(volume float64) float64 {
+		trade, ok := evaluate(volume)
+		last = trade
+		if !ok {
+			return math.Inf(-1)
+		}
+		if !found || trade.NetProfitEUR > best.NetProfitEUR {
+			best, bestVolume, found = trade, volume, true
+		}
+		return trade.NetProfitEUR
+	}
+
+	var curve []model.ProfitSample
+	step := max(limit, 0) / float64(z.samples)
+	for i := 1; i <= z.samples; i++ {
+		volume := step * float64(i)
+		if profit := try(volume); !math.IsInf(profit, -1) {
+			curve = append(curve, model.ProfitSample{VolumeEUR: volume, NetProfitEUR: profit})
+		}
+	}
+	if !found {
+		return last, 0, false
+	}
+
+	lo, hi := max(bestVolume-step, 0), min(bestVolume+step, limit)
+	a, b := hi-invPhi*(hi-lo), lo+invPhi*(hi-lo)
+	fa, fb := try(a), try(b)
+	for range sizingRefinements {
+		if fa >= fb {
+			hi, b, fb = b, a, fa
+			a = hi - invPhi*(hi-lo)
+			fa = try(a)
+		} else {
+			lo, a, fa = a, b, fb
+			b = lo + invPhi*(hi-lo)
+			fb = try(b)
+		}
+	}
+
+	best.ProfitCurve = curve
+	return best, bestVolume, true
+}
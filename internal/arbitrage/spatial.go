@@ -0,0 +1,258 @@
+package arbitrage
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+
+	"referee/internal/config"
+	"referee/internal/fees"
+	"referee/internal/instrument"
+	"referee/internal/inventory"
+	"referee/internal/model"
+)
+
+// spatialStrategy buys a pair on one exchange and sells it on another whenever one
+// exchange's ask is below another's bid.
+type spatialStrategy struct {
+	logger          *slog.Logger
+	cfg             *config.Config
+	instruments     *instrument.Registry
+	fees            *fees.Model
+	inventory       *inventory.Inventory
+	name            string
+	tradeVolumeEUR  float64
+	minNetProfitEUR float64
+	sizer           sizer
+}
+
+func newSpatialStrategy(logger *slog.Logger, cfg *config.Config, instruments *instrument.Registry, feeModel *fees.Model, inv *inventory.Inventory, strategyCfg config.StrategyConfig) *spatialStrategy {
+	return &spatialStrategy{
+		logger:          logger,
+		cfg:             cfg,
+		instruments:     instruments,
+		fees:            feeModel,
+		inventory:       inv,
+		sizer:           newSizer(cfg.Arbitrage.Sizing, strategyCfg),
+		name:            strategyCfg.Name,
+		tradeVolumeEUR:  strategyCfg.TradeVolumeEUR,
+		minNetProfitEUR: strategyCfg.MinNetProfitEUR,
+	}
+}
+
+func (s *spatialStrategy) Name() string {
+	return s.name
+}
+
+func (s *spatialStrategy) Params() string {
+	return fmt.Sprintf("trade_volume_eur=%g min_net_profit_eur=%g", s.tradeVolumeEUR, s.minNetProfitEUR) + s.sizer.params()
+}
+
+// Detect compares the tick with the quotes of the same pair on other exchanges.
+func (s *spatialStrategy) Detect(market *Market, tick model.PriceTick) []Opportunity {
+	var opportunities []Opportunity
+	for exchange, latestTick := range market.Quotes(tick.Pair) {
+		if exchange == tick.Exchange {
+			continue // Skip comparing with itself
+		}
+
+		// Check if we can buy on one exchange and sell on another
+		var buyTick, sellTick model.PriceTick
+		if tick.Ask < latestTick.Bid {
+			// Buy on tick.Exchange, sell on exchange
+			buyTick, sellTick = tick, latestTick
+		} else if latestTick.Ask < tick.Bid {
+			// Buy on exchange, sell on tick.Exchange
+			buyTick, sellTick = latestTick, tick
+		} else {
+			continue
+		}
+
+		// The configured volumes are in EUR, but the trade spends the pair's quote asset
+		inst, _ := s.instruments.Get(buyTick.Pair)
+		rate, priced := market.ValueIn(inst.Quote, "EUR", 1)
+		trade, volume, ok := spatialTrade(buyTick, sellTick), 0.0, false
+		if priced {
+			trade, volume, ok = s.evaluate(market, buyTick, sellTick, rate)
+		}
+		// Never trade against a quote the feed stopped updating
+		fresh := !market.IsStale(tick.Pair, exchange)
+		opportunities = append(opportunities, Opportunity{
+			Trade:  trade,
+			Reason: rejection(fresh, priced, ok, trade.NetProfitEUR, s.minNetProfitEUR*rate),
+			Quotes: []quoteKey{
+				{Pair: buyTick.Pair, Exchange: buyTick.Exchange},
+				{Pair: sellTick.Pair, Exchange: sellTick.Exchange},
+			},
+			Reprice: func(market *Market) (model.SimulatedTrade, bool) {
+				return s.reprice(market, trade, volume)
+			},
+		})
+	}
+	return opportunities
+}
+
+// reprice evaluates a detected trade at the volume chosen at detection against the
+// latest quotes. Stale quotes make the trade unexecutable.
+func (s *spatialStrategy) reprice(market *Market, detected model.SimulatedTrade, volumeEUR float64) (model.SimulatedTrade, bool) {
+	buyTick, buyFresh := market.Quote(detected.TradingPair, detected.BuyExchange)
+	sellTick, sellFresh := market.Quote(detected.TradingPair, detected.SellExchange)
+	trade, ok := s.evaluateVolume(market, buyTick, sellTick, volumeEUR)
+	return trade, ok && buyFresh && sellFresh
+}
+
+// evaluate prices a trade at the configured volume or, with sizing enabled, at the most
+// profitable volume the limits allow. rate is the price of one EUR in the quote asset,
+// which converts the configured volumes. It also returns the volume it chose, in the
+// quote asset.
+func (s *spatialStrategy) evaluate(market *Market, buyTick, sellTick model.PriceTick, rate float64) (model.SimulatedTrade, float64, bool) {
+	if !s.sizer.enabled {
+		volume := s.tradeVolumeEUR * rate
+		trade, ok := s.evaluateVolume(market, buyTick, sellTick, volume)
+		return trade, volume, ok
+	}
+	return s.sizer.optimize(s.volumeLimit(buyTick, sellTick, s.sizer.maxVolume*rate), func(volumeEUR float64) (model.SimulatedTrade, bool) {
+		return s.evaluateVolume(market, buyTick, sellTick, volumeEUR)
+	})
+}
+
+// volumeLimit is the largest volume the sizer may choose, up to limit. With inventory,
+// the buy leg cannot spend more than the quote asset held and the sell leg cannot sell
+// more than the base asset held.
+func (s *spatialStrategy) volumeLimit(buyTick, sellTick model.PriceTick, limit float64) float64 {
+	if s.inventory == nil {
+		return limit
+	}
+	inst, ok := s.instruments.Get(buyTick.Pair)
+	if !ok {
+		return 0
+	}
+	// The buy leg's fee is paid on top of the volume
+	quote := s.inventory.Balance(buyTick.Exchange, inst.Quote)
+	limit = min(limit, quote/(1+s.fees.TakerRate(buyTick.Exchange, buyTick.Pair)))
+	base := s.inventory.Balance(sellTick.Exchange, inst.Base)
+	if cost := buyBase(buyTick.Asks, buyTick.Ask, base); cost.Complete {
+		limit = min(limit, cost.QuoteQty)
+	}
+	return limit
+}
+
+// evaluateVolume prices a trade spending volumeEUR of the quote asset buying on buyTick's
+// exchange and selling on sellTick's exchange. Both legs are filled by walking the order book, so the volume
+// pays for the liquidity it actually consumes rather than assuming everything fills at the touch.
+// The quantity is rounded down to the coarser lot size of the two listings.
+// The second return value is false if either book is too thin to fill the volume, either
+// leg falls below its exchange's minimum notional, or the withdrawal fee cannot be valued.
+func (s *spatialStrategy) evaluateVolume(market *Market, buyTick, sellTick model.PriceTick, volumeEUR float64) (model.SimulatedTrade, bool) {
+	buyExchange, sellExchange := buyTick.Exchange, sellTick.Exchange
+	buyPrice, sellPrice := buyTick.Ask, sellTick.Bid
+	trade := spatialTrade(buyTick, sellTick)
+	buyListing, err := s.instruments.Listing(buyExchange, buyTick.Pair)
+	if err != nil {
+		return trade, false
+	}
+	sellListing, err := s.instruments.Listing(sellExchange, sellTick.Pair)
+	if err != nil {
+		return trade, false
+	}
+
+	// Size the trade from the EUR volume, then buy it on one book and sell it into the other
+	sizing := buyWithQuote(buyTick.Asks, buyPrice, volumeEUR)
+	if !sizing.Complete {
+		s.logger.Debug("Insufficient depth on buy side", "exchange", buyExchange)
+		return trade, false
+	}
+	qty := roundToLot(sizing.BaseQty, math.Max(buyListing.LotSize, sellListing.LotSize))
+	if qty <= 0 {
+		return trade, false
+	}
+	buyFill := buyBase(buyTick.Asks, buyPrice, qty)
+	sellFill := sellBase(sellTick.Bids, sellPrice, qty)
+	if !buyFill.Complete || !sellFill.Complete {
+		s.logger.Debug("Insufficient depth to fill both legs", "buyExchange", buyExchange, "sellExchange", sellExchange)
+		return trade, false
+	}
+	if buyFill.QuoteQty < buyListing.MinNotional || sellFill.QuoteQty < sellListing.MinNotional {
+		s.logger.Debug("Trade below minimum notional", "buyExchange", buyExchange, "sellExchange", sellExchange)
+		return trade, false
+	}
+	volumeInCrypto := buyFill.BaseQty
+	grossProfitEUR := sellFill.QuoteQty - buyFill.QuoteQty
+
+	// Slippage is the cost of walking the book compared to filling everything at the touch
+	slippageEUR := (buyFill.VWAP-buyPrice)*volumeInCrypto + (sellPrice-sellFill.VWAP)*volumeInCrypto
+
+	// Calculate fees at the exchanges' current volume tiers
+	buyLegFee := buyFill.QuoteQty * s.fees.TakerRate(buyExchange, buyTick.Pair)
+	sellLegFee := sellFill.QuoteQty * s.fees.TakerRate(sellExchange, sellTick.Pair)
+	totalFeesEUR := buyLegFee + sellLegFee
+	inst, _ := s.instruments.Get(buyTick.Pair)
+	// Without pre-funded inventory the coins have to be moved for every trade
+	if !s.cfg.Arbitrage.Inventory.Enabled {
+		withdrawalFee, ok := s.withdrawalFee(market, buyExchange, inst)
+		if !ok {
+			return trade, false
+		}
+		trade.WithdrawalFeeEUR = withdrawalFee
+		totalFeesEUR += withdrawalFee
+	}
+
+	// Calculate net profit
+	netProfitEUR := grossProfitEUR - totalFeesEUR
+
+	trade.VolumeEUR = buyFill.QuoteQty
+	trade.GrossProfitEUR = grossProfitEUR
+	trade.TotalFeesEUR = totalFeesEUR
+	trade.NetProfitEUR = netProfitEUR
+	trade.BuyVWAP = buyFill.VWAP
+	trade.SellVWAP = sellFill.VWAP
+	trade.BuyLevelsConsumed = buyFill.Levels
+	trade.SellLevelsConsumed = sellFill.Levels
+	trade.SlippageEUR = slippageEUR
+	trade.Legs = []model.TradeLeg{
+		{
+			Exchange: buyExchange, Pair: buyTick.Pair, BaseAsset: inst.Base, QuoteAsset: inst.Quote, Buy: true,
+			BaseQty: buyFill.BaseQty, QuoteQty: buyFill.QuoteQty, Fee: buyLegFee, FeeAsset: inst.Quote,
+		},
+		{
+			Exchange: sellExchange, Pair: sellTick.Pair, BaseAsset: inst.Base, QuoteAsset: inst.Quote, Buy: false,
+			BaseQty: sellFill.BaseQty, QuoteQty: sellFill.QuoteQty, Fee: sellLegFee, FeeAsset: inst.Quote,
+		},
+	}
+	return trade, true
+}
+
+// spatialTrade returns a trade buying at buyTick's ask and selling at sellTick's bid,
+// before it has been priced.
+func spatialTrade(buyTick, sellTick model.PriceTick) model.SimulatedTrade {
+	return model.SimulatedTrade{
+		TradeType:    TradeTypeSpatial,
+		TradingPair:  buyTick.Pair,
+		BuyExchange:  buyTick.Exchange,
+		SellExchange: sellTick.Exchange,
+		BuyPrice:     buyTick.Ask,
+		SellPrice:    sellTick.Bid,
+	}
+}
+
+// withdrawalFee returns the cost, in the quote asset, of withdrawing the bought coins
+// from the exchange. A fee configured in units of the base asset is converted at the
+// current mid price, so it follows the market; otherwise network_withdrawal_fee_eur
+// applies, converted from EUR. The second return value is false if neither can be valued.
+func (s *spatialStrategy) withdrawalFee(market *Market, exchange string, inst instrument.Instrument) (float64, bool) {
+	if fee, ok := s.cfg.WithdrawalFee(exchange, inst.Base); ok {
+		if value, ok := market.ValueIn(inst.Quote, inst.Base, fee); ok {
+			return value, true
+		}
+		s.logger.Warn("No price to value withdrawal fee", "exchange", exchange, "asset", inst.Base)
+	}
+	feeEUR := s.cfg.Arbitrage.NetworkWithdrawalFeeEUR
+	if feeEUR == 0 {
+		return 0, true
+	}
+	value, ok := market.ValueIn(inst.Quote, "EUR", feeEUR)
+	if !ok {
+		s.logger.Warn("No price to value network withdrawal fee", "exchange", exchange, "quote", inst.Quote)
+	}
+	return value, ok
+}
@@ -0,0 +1,110 @@
+package arbitrage
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"referee/internal/config"
+	"referee/internal/fees"
+	"referee/internal/instrument"
+	"referee/internal/inventory"
+	"referee/internal/model"
+)
+
+// Strategy detects arbitrage opportunities in the market. Every strategy sees the same
+// tick feed; the engine schedules and settles the opportunities they emit.
+type Strategy interface {
+	// Name identifies the strategy instance, e.g. "spatial-large".
+	Name() string
+	// Params describes the parameter set, so instances of one type can be compared.
+	Params() string
+	// Detect returns the crossed-book opportunities after tick has been applied to market,
+	// including those that cannot be acted on.
+	Detect(market *Market, tick model.PriceTick) []Opportunity
+}
+
+// Reasons recorded for every opportunity. A strategy rejects an opportunity as stale,
+// unpriced, unfillable or unprofitable; the engine records the outcome of the others.
+const (
+	OpportunityExecuted         = "executed"
+	OpportunityMissed           = "missed"
+	OpportunityUnprofitable     = "unprofitable"
+	OpportunityUnfillable       = "unfillable"
+	OpportunityStale            = "stale"
+	OpportunityUnpriced         = "unpriced" // No EUR rate to size it in the asset it spends
+	OpportunityInventoryBlocked = "inventory_blocked"
+	OpportunityDuplicate        = "duplicate"    // Its episode already submitted as many trades as allowed
+	OpportunityRiskBlocked      = "risk_blocked" // A risk limit or the kill switch stopped it
+	OpportunityCancelled        = "cancelled"    // The engine shut down before its latency elapsed
+)
+
+// Opportunity is a trade detected by a strategy where the books crossed.
+type Opportunity struct {
+	Trade model.SimulatedTrade
+	// Reason is why the strategy rejected the trade; empty if it should be executed
+	Reason string
+	// Quotes are the quotes the trade was priced from. The opportunity's episode ends on
+	// the first tick of one of them that no longer produces it.
+	Quotes []quoteKey
+	// Reprice evaluates the same trade against the market once the simulated latency has
+	// elapsed. It returns false if the trade can no longer be executed.
+	Reprice func(market *Market) (model.SimulatedTrade, bool)
+}
+
+// NewStrategies creates the strategies listed in the configuration.
+// Every strategy prices fees with the shared fee model. inv is nil unless inventory
+// simulation is enabled; otherwise it bounds the size of trades.
+func NewStrategies(logger *slog.Logger, cfg *config.Config, instruments *instrument.Registry, feeModel *fees.Model, inv *inventory.Inventory) ([]Strategy, error) {
+	var strategies []Strategy
+	names := make(map[string]bool)
+	for _, strategyCfg := range cfg.Arbitrage.StrategyConfigs() {
+		if strategyCfg.Name == "" {
+			strategyCfg.Name = strategyCfg.Type
+		}
+		if names[strategyCfg.Name] {
+			return nil, fmt.Errorf("duplicate strategy name: %s", strategyCfg.Name)
+		}
+		names[strategyCfg.Name] = true
+		if strategyCfg.TradeVolumeEUR == 0 {
+			strategyCfg.TradeVolumeEUR = cfg.Arbitrage.SimulatedTradeVolumeEUR
+		}
+
+		strategy, err := newStrategy(logger, cfg, instruments, feeModel, inv, strategyCfg)
+		if err != nil {
+			return nil, err
+		}
+		strategies = append(strategies, strategy)
+	}
+	return strategies, nil
+}
+
+// rejection returns why a crossed-book trade should not be executed, or "" if it should.
+// fresh is false if any quote was stale, priced false if the trade could not be sized
+// for lack of an EUR rate, and fillable false if the books or listings could not fill
+// the trade. The profits are in the same asset.
+func rejection(fresh, priced, fillable bool, netProfitEUR, minNetProfitEUR float64) string {
+	switch {
+	case !fresh:
+		return OpportunityStale
+	case !priced:
+		return OpportunityUnpriced
+	case !fillable:
+		return OpportunityUnfillable
+	case netProfitEUR <= minNetProfitEUR:
+		return OpportunityUnprofitable
+	default:
+		return ""
+	}
+}
+
+func newStrategy(logger *slog.Logger, cfg *config.Config, instruments *instrument.Registry, feeModel *fees.Model, inv *inventory.Inventory, strategyCfg config.StrategyConfig) (Strategy, error) {
+	switch strings.ToLower(strategyCfg.Type) {
+	case TradeTypeSpatial:
+		return newSpatialStrategy(logger, cfg, instruments, feeModel, inv, strategyCfg), nil
+	case TradeTypeTriangular:
+		return newTriangularStrategy(logger, cfg, instruments, feeModel, inv, strategyCfg), nil
+	default:
+		return nil, fmt.Errorf("unsupported strategy type: %s", strategyCfg.Type)
+	}
+}
@@ -0,0 +1,341 @@
+package arbitrage
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"referee/internal/config"
+	"referee/internal/fees"
+	"referee/internal/instrument"
+	"referee/internal/inventory"
+	"referee/internal/model"
+)
+
+// triangleLeg converts one asset of a cycle into the next by trading a single pair.
+type triangleLeg struct {
+	Pair string
+	Buy  bool // True if the leg buys the pair's base asset with its quote asset
+}
+
+// triangle is a three-pair cycle on one exchange that starts and ends in the same asset,
+// e.g. EUR>BTC>ETH>EUR via BTC/EUR, ETH/BTC and ETH/EUR.
+type triangle struct {
+	Exchange string
+	Assets   [4]string
+	Legs     [3]triangleLeg
+}
+
+// Route returns the assets the cycle passes through, e.g. "EUR>BTC>ETH>EUR".
+func (t triangle) Route() string {
+	return strings.Join(t.Assets[:], ">")
+}
+
+// quoteKeys returns the quotes of the cycle's three pairs.
+func (t triangle) quoteKeys() []quoteKey {
+	keys := make([]quoteKey, 0, len(t.Legs))
+	for _, leg := range t.Legs {
+		keys = append(keys, quoteKey{Pair: leg.Pair, Exchange: t.Exchange})
+	}
+	return keys
+}
+
+// crossed reports whether the cycle returns more than it starts with at the top of
+// book, before fees.
+func (t triangle) crossed(ticks [3]model.PriceTick) bool {
+	rate := 1.0
+	for i, leg := range t.Legs {
+		if leg.Buy {
+			if ticks[i].Ask <= 0 {
+				return false
+			}
+			rate /= ticks[i].Ask
+		} else {
+			rate *= ticks[i].Bid
+		}
+	}
+	return rate > 1
+}
+
+// triangularStrategy trades cycles through three pairs on a single exchange whose product
+// of rates beats the three taker fees.
+type triangularStrategy struct {
+	logger          *slog.Logger
+	cfg             *config.Config
+	instruments     *instrument.Registry
+	fees            *fees.Model
+	inventory       *inventory.Inventory
+	name            string
+	tradeVolumeEUR  float64
+	minNetProfitEUR float64
+	sizer           sizer
+	startAsset      string
+	exchanges       []string
+	triangles       map[quoteKey][]triangle // Cycles by the quote streams they trade
+}
+
+func newTriangularStrategy(logger *slog.Logger, cfg *config.Config, instruments *instrument.Registry, feeModel *fees.Model, inv *inventory.Inventory, strategyCfg config.StrategyConfig) *triangularStrategy {
+	s := &triangularStrategy{
+		logger:          logger,
+		cfg:             cfg,
+		instruments:     instruments,
+		fees:            feeModel,
+		inventory:       inv,
+		sizer:           newSizer(cfg.Arbitrage.Sizing, strategyCfg),
+		name:            strategyCfg.Name,
+		tradeVolumeEUR:  strategyCfg.TradeVolumeEUR,
+		minNetProfitEUR: strategyCfg.MinNetProfitEUR,
+		startAsset:      strings.ToUpper(strategyCfg.StartAsset),
+		exchanges:       strategyCfg.Exchanges,
+	}
+	if s.startAsset == "" {
+		s.startAsset = "EUR"
+	}
+	s.triangles = s.buildTriangles()
+	return s
+}
+
+func (s *triangularStrategy) Name() string {
+	return s.name
+}
+
+func (s *triangularStrategy) Params() string {
+	return fmt.Sprintf("trade_volume_eur=%g min_net_profit_eur=%g start_asset=%s exchanges=%s",
+		s.tradeVolumeEUR, s.minNetProfitEUR, s.startAsset, strings.Join(s.exchanges, ",")) + s.sizer.params()
+}
+
+// findTriangles returns every cycle from startAsset through two other assets and back
+// that can be traded with the given pairs. Both directions of a cycle are returned.
+func (s *triangularStrategy) findTriangles(exchange, startAsset string, pairs []string) []triangle {
+	// conversions[from][to] is the leg that turns asset from into asset to
+	conversions := make(map[string]map[string]triangleLeg)
+	addConversion := func(from, to string, leg triangleLeg) {
+		if conversions[from] == nil {
+			conversions[from] = make(map[string]triangleLeg)
+		}
+		conversions[from][to] = leg
+	}
+	for _, pair := range pairs {
+		inst, ok := s.instruments.Get(pair)
+		if !ok {
+			continue
+		}
+		addConversion(inst.Quote, inst.Base, triangleLeg{Pair: pair, Buy: true})
+		addConversion(inst.Base, inst.Quote, triangleLeg{Pair: pair, Buy: false})
+	}
+
+	var triangles []triangle
+	for first, firstLeg := range conversions[startAsset] {
+		for second, secondLeg := range conversions[first] {
+			if second == startAsset {
+				continue
+			}
+			closingLeg, ok := conversions[second][startAsset]
+			if !ok {
+				continue
+			}
+			triangles = append(triangles, triangle{
+				Exchange: exchange,
+				Assets:   [4]string{startAsset, first, second, startAsset},
+				Legs:     [3]triangleLeg{firstLeg, secondLeg, closingLeg},
+			})
+		}
+	}
+	// Map iteration order is random; keep detection order deterministic
+	slices.SortFunc(triangles, func(a, b triangle) int { return strings.Compare(a.Route(), b.Route()) })
+	return triangles
+}
+
+// buildTriangles indexes the cycles on the configured exchanges by the quote streams they depend on.
+func (s *triangularStrategy) buildTriangles() map[quoteKey][]triangle {
+	index := make(map[quoteKey][]triangle)
+	for exchange := range s.cfg.Exchanges {
+		if len(s.exchanges) > 0 && !slices.Contains(s.exchanges, exchange) {
+			continue
+		}
+		for _, tri := range s.findTriangles(exchange, s.startAsset, s.cfg.PairsFor(exchange)) {
+			s.logger.Info("Watching triangular cycle", "strategy", s.name, "exchange", exchange, "route", tri.Route())
+			for _, leg := range tri.Legs {
+				key := quoteKey{Pair: leg.Pair, Exchange: exchange}
+				index[key] = append(index[key], tri)
+			}
+		}
+	}
+	return index
+}
+
+// Detect evaluates every cycle that trades the tick's pair on the tick's exchange.
+func (s *triangularStrategy) Detect(market *Market, tick model.PriceTick) []Opportunity {
+	var opportunities []Opportunity
+	for _, tri := range s.triangles[quoteKey{Pair: tick.Pair, Exchange: tick.Exchange}] {
+		ticks, fresh := s.quotes(market, tri)
+		if !tri.crossed(ticks) {
+			continue
+		}
+		// The configured volumes are in EUR, but the cycle spends the start asset
+		rate, priced := market.ValueIn(s.startAsset, "EUR", 1)
+		trade, volume, ok := triangleTrade(tri, ticks), 0.0, false
+		if priced {
+			trade, volume, ok = s.evaluate(tri, ticks, rate)
+		}
+		opportunities = append(opportunities, Opportunity{
+			Trade:  trade,
+			Reason: rejection(fresh, priced, ok, trade.NetProfitEUR, s.minNetProfitEUR*rate),
+			Quotes: tri.quoteKeys(),
+			Reprice: func(market *Market) (model.SimulatedTrade, bool) {
+				return s.reprice(market, tri, volume)
+			},
+		})
+	}
+	return opportunities
+}
+
+// quotes returns the latest quotes of the cycle's three pairs, and false if any of
+// them is missing or stale.
+func (s *triangularStrategy) quotes(market *Market, tri triangle) ([3]model.PriceTick, bool) {
+	var ticks [3]model.PriceTick
+	fresh := true
+	for i, leg := range tri.Legs {
+		tick, ok := market.Quote(leg.Pair, tri.Exchange)
+		fresh = fresh && ok
+		ticks[i] = tick
+	}
+	return ticks, fresh
+}
+
+// reprice evaluates a cycle at the volume chosen at detection against the latest
+// quotes of its three pairs. Missing or stale quotes make the cycle unexecutable.
+func (s *triangularStrategy) reprice(market *Market, tri triangle, volume float64) (model.SimulatedTrade, bool) {
+	ticks, fresh := s.quotes(market, tri)
+	trade, ok := s.evaluateVolume(tri, ticks, volume)
+	return trade, ok && fresh
+}
+
+// evaluate prices a cycle at the configured volume or, with sizing enabled, at the most
+// profitable volume the limits allow. rate is the price of one EUR in the start asset,
+// which converts the configured volumes. With inventory, the cycle cannot spend more
+// of the start asset than the exchange holds. It also returns the volume it chose, in
+// the start asset.
+func (s *triangularStrategy) evaluate(tri triangle, ticks [3]model.PriceTick, rate float64) (model.SimulatedTrade, float64, bool) {
+	if !s.sizer.enabled {
+		volume := s.tradeVolumeEUR * rate
+		trade, ok := s.evaluateVolume(tri, ticks, volume)
+		return trade, volume, ok
+	}
+	limit := s.sizer.maxVolume * rate
+	if s.inventory != nil {
+		limit = min(limit, s.inventory.Balance(tri.Exchange, s.startAsset))
+	}
+	return s.sizer.optimize(limit, func(volume float64) (model.SimulatedTrade, bool) {
+		return s.evaluateVolume(tri, ticks, volume)
+	})
+}
+
+// evaluateVolume prices a cycle that spends start of the start asset on the first leg
+// and converts the proceeds of each leg into the next. Every leg walks its order book
+// and pays the pair's taker fee on what it receives. Funds never leave the exchange,
+// so no withdrawal fee applies.
+func (s *triangularStrategy) evaluateVolume(tri triangle, ticks [3]model.PriceTick, start float64) (model.SimulatedTrade, bool) {
+	trade := triangleTrade(tri, ticks)
+	var feeRates, noFees [3]float64
+	for i, leg := range tri.Legs {
+		feeRates[i] = s.fees.TakerRate(tri.Exchange, leg.Pair)
+	}
+
+	fills, net, ok := s.walkTriangle(tri, ticks, start, feeRates)
+	if !ok {
+		s.logger.Debug("Insufficient depth to fill every leg", "exchange", tri.Exchange, "route", tri.Route())
+		return trade, false
+	}
+	_, gross, _ := s.walkTriangle(tri, ticks, start, noFees)
+
+	// Slippage is the cost of walking the books compared to filling everything at the touch
+	var touch [3]model.PriceTick
+	for i, tick := range ticks {
+		touch[i] = model.PriceTick{Exchange: tick.Exchange, Pair: tick.Pair, Bid: tick.Bid, Ask: tick.Ask}
+	}
+	_, touchGross, _ := s.walkTriangle(tri, touch, start, noFees)
+
+	trade.VolumeEUR = start
+	trade.GrossProfitEUR = gross - start
+	trade.NetProfitEUR = net - start
+	trade.TotalFeesEUR = gross - net
+	trade.SlippageEUR = touchGross - gross
+	trade.BuyVWAP = fills[0].VWAP
+	trade.SellVWAP = fills[2].VWAP
+	trade.BuyLevelsConsumed = fills[0].Levels
+	trade.SellLevelsConsumed = fills[2].Levels
+	for i, leg := range tri.Legs {
+		inst, _ := s.instruments.Get(leg.Pair)
+		tradeLeg := model.TradeLeg{
+			Exchange: tri.Exchange, Pair: leg.Pair, BaseAsset: inst.Base, QuoteAsset: inst.Quote, Buy: leg.Buy,
+			BaseQty: fills[i].BaseQty, QuoteQty: fills[i].QuoteQty,
+		}
+		// The fee is taken from what each leg receives
+		if leg.Buy {
+			tradeLeg.Fee, tradeLeg.FeeAsset = fills[i].BaseQty*feeRates[i], inst.Base
+		} else {
+			tradeLeg.Fee, tradeLeg.FeeAsset = fills[i].QuoteQty*feeRates[i], inst.Quote
+		}
+		trade.Legs = append(trade.Legs, tradeLeg)
+	}
+	return trade, true
+}
+
+// triangleTrade returns a trade of the cycle at the touch prices of its first and last
+// legs, before it has been priced.
+func triangleTrade(tri triangle, ticks [3]model.PriceTick) model.SimulatedTrade {
+	first, last := tri.Legs[0], tri.Legs[2]
+	trade := model.SimulatedTrade{
+		TradeType:    TradeTypeTriangular,
+		Route:        tri.Route(),
+		TradingPair:  first.Pair,
+		BuyExchange:  tri.Exchange,
+		SellExchange: tri.Exchange,
+		BuyPrice:     ticks[0].Ask,
+		SellPrice:    ticks[2].Ask,
+	}
+	if !first.Buy {
+		trade.BuyPrice = ticks[0].Bid
+	}
+	if !last.Buy {
+		trade.SellPrice = ticks[2].Bid
+	}
+	return trade
+}
+
+// walkTriangle converts amount of the start asset through the three legs, deducting
+// each leg's fee rate from its proceeds. It returns the fills, the amount of the start
+// asset received back, and false if a book ran out of liquidity or a leg fell below the
+// listing's minimum size. Base quantities are rounded down to the listing's lot size on
+// every leg: a buy leg only buys whole lots with what it is given, and a sell leg only
+// sells whole lots of what it holds. Whatever cannot be traded is left behind and
+// counts as a loss.
+func (s *triangularStrategy) walkTriangle(tri triangle, ticks [3]model.PriceTick, amount float64, feeRates [3]float64) ([3]fill, float64, bool) {
+	var fills [3]fill
+	for i, leg := range tri.Legs {
+		listing, err := s.instruments.Listing(tri.Exchange, leg.Pair)
+		if err != nil {
+			return fills, 0, false
+		}
+		tick := ticks[i]
+		var f fill
+		if leg.Buy {
+			f = buyWithQuote(tick.Asks, tick.Ask, amount)
+			if listing.LotSize > 0 {
+				f = buyBase(tick.Asks, tick.Ask, roundToLot(f.BaseQty, listing.LotSize))
+			}
+			amount = f.BaseQty
+		} else {
+			f = sellBase(tick.Bids, tick.Bid, roundToLot(amount, listing.LotSize))
+			amount = f.QuoteQty
+		}
+		if !f.Complete || f.BaseQty <= 0 || f.QuoteQty < listing.MinNotional {
+			return fills, 0, false
+		}
+		fills[i] = f
+		amount *= 1 - feeRates[i]
+	}
+	return fills, amount, true
+}
@@ -0,0 +1,113 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the passage of time so that components can run against the wall
+// clock in production and against a virtual clock in backtests and tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// Advancer is implemented by clocks that can be moved forward explicitly, such as
+// a Simulated clock driven by replayed tick timestamps.
+type Advancer interface {
+	AdvanceTo(t time.Time)
+}
+
+// Real is a Clock backed by the system time.
+type Real struct{}
+
+// NewReal creates a Clock backed by the system time.
+func NewReal() Real {
+	return Real{}
+}
+
+// Now returns the current system time.
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for the duration to elapse and then sends the current time on the returned channel.
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Simulated is a virtual Clock that only moves when advanced explicitly, typically
+// to the timestamps of replayed ticks. It is safe for concurrent use.
+type Simulated struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+type waiter struct {
+	due time.Time
+	ch  chan time.Time
+}
+
+// NewSimulated creates a virtual clock starting at the given time.
+func NewSimulated(start time.Time) *Simulated {
+	return &Simulated{now: start}
+}
+
+// Now returns the current virtual time.
+func (s *Simulated) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// After returns a channel that receives the virtual time once the clock has been
+// advanced by at least d. A non-positive duration fires immediately.
+func (s *Simulated) After(d time.Duration) <-chan time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- s.now
+		return ch
+	}
+	s.waiters = append(s.waiters, waiter{due: s.now.Add(d), ch: ch})
+	return ch
+}
+
+// Waiters returns the number of channels returned by After that have not fired yet.
+func (s *Simulated) Waiters() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.waiters)
+}
+
+// Advance moves the clock forward by d.
+func (s *Simulated) Advance(d time.Duration) {
+	s.AdvanceTo(s.Now().Add(d))
+}
+
+// AdvanceTo moves the clock forward to t and fires every waiter that has become due.
+// Times before the current virtual time are ignored so the clock never runs backwards.
+func (s *Simulated) AdvanceTo(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !t.After(s.now) {
+		return
+	}
+	s.now = t
+
+	sort.Slice(s.waiters, func(i, j int) bool { return s.waiters[i].due.Before(s.waiters[j].due) })
+	remaining := s.waiters[:0]
+	for _, w := range s.waiters {
+		if w.due.After(t) {
+			remaining = append(remaining, w)
+			continue
+		}
+		w.ch <- t
+	}
+	s.waiters = remaining
+}
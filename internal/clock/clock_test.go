@@ -0,0 +1,30 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulated(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := NewSimulated(start)
+	assert.Equal(t, start, clk.Now())
+
+	immediate := clk.After(0)
+	assert.Len(t, immediate, 1)
+
+	later := clk.After(100 * time.Millisecond)
+	clk.Advance(50 * time.Millisecond)
+	assert.Len(t, later, 0)
+	assert.Equal(t, 1, clk.Waiters())
+
+	clk.Advance(50 * time.Millisecond)
+	assert.Equal(t, start.Add(100*time.Millisecond), <-later)
+	assert.Equal(t, 0, clk.Waiters())
+
+	// The clock never runs backwards
+	clk.AdvanceTo(start)
+	assert.Equal(t, start.Add(100*time.Millisecond), clk.Now())
+}
@@ -4,22 +4,196 @@ import (
 	"fmt"
 	"github.com/spf13/viper"
 	"strings"
+	"time"
 )
 
 // Config stores all configuration for the application.
 // The values are read by viper from a config file or environment variables.
 type Config struct {
-	Arbitrage ArbitrageConfig
-	Database  DatabaseConfig
-	Exchanges map[string]ExchangeConfig
+	Arbitrage   ArbitrageConfig
+	Database    DatabaseConfig
+	Exchanges   map[string]ExchangeConfig
+	Instruments InstrumentsConfig
 }
 
 // ArbitrageConfig defines the arbitrage-related settings.
 type ArbitrageConfig struct {
-	SimulatedTradeVolumeEUR float64 `mapstructure:"simulated_trade_volume_eur"`
-	NetworkWithdrawalFeeEUR float64 `mapstructure:"network_withdrawal_fee_eur"`
-	SimulatedLatencyMS      int     `mapstructure:"simulated_latency_ms"`
-	TradingPair             string  `mapstructure:"trading_pair"`
+	SimulatedTradeVolumeEUR float64          `mapstructure:"simulated_trade_volume_eur"`
+	NetworkWithdrawalFeeEUR float64          `mapstructure:"network_withdrawal_fee_eur"`
+	SimulatedLatencyMS      int              `mapstructure:"simulated_latency_ms"`
+	TradingPair             string           `mapstructure:"trading_pair"` // Deprecated: use TradingPairs
+	TradingPairs            []string         `mapstructure:"trading_pairs"`
+	MaxQuoteAgeMS           int              `mapstructure:"max_quote_age_ms"`
+	Triangular              TriangularConfig `mapstructure:"triangular"`
+	// Strategies lists the strategies to run side by side. When empty, the spatial
+	// strategy runs, plus the triangular one if it is enabled.
+	Strategies []StrategyConfig `mapstructure:"strategies"`
+	Inventory  InventoryConfig  `mapstructure:"inventory"`
+	Sizing     SizingConfig     `mapstructure:"sizing"`
+	Episodes   EpisodesConfig   `mapstructure:"episodes"`
+	Risk       RiskConfig       `mapstructure:"risk"`
+	TickFilter TickFilterConfig `mapstructure:"tick_filter"`
+}
+
+// TickFilterConfig tunes the validation of incoming ticks before they reach the engine.
+// Crossed quotes and quotes without a positive price are always rejected.
+type TickFilterConfig struct {
+	// MaxDeviationPercent rejects quotes whose mid price is further than this from the
+	// median of the latest mid price of every exchange quoting the pair. Defaults to 5;
+	// a negative value disables the check.
+	MaxDeviationPercent float64 `mapstructure:"max_deviation_percent"`
+	// WindowMS is how long an exchange's latest mid price counts towards the median.
+	// Defaults to one minute.
+	WindowMS int `mapstructure:"window_ms"`
+	// MinExchanges is how many exchanges must have quoted the pair within the window
+	// before outliers are rejected. Defaults to 2.
+	MinExchanges int `mapstructure:"min_exchanges"`
+}
+
+// RiskConfig sets the limits checked before every simulated execution. A limit of zero
+// is disabled. Blocked trades and kill switch changes are recorded as risk events.
+type RiskConfig struct {
+	// MaxTradesPerMinute caps the trades submitted in any rolling minute.
+	MaxTradesPerMinute int `mapstructure:"max_trades_per_minute"`
+	// MaxDailyLossEUR stops trading for the rest of the UTC day once the realized
+	// PnL of trades and transfer fees falls this far below zero.
+	MaxDailyLossEUR float64 `mapstructure:"max_daily_loss_eur"`
+	// MaxExposureEUR caps the notional traded on any one exchange. Trades add to it
+	// once submitted, and only rebalancing transfers moving funds off the exchange
+	// reduce it, so without rebalancing it caps the notional traded over the run.
+	MaxExposureEUR float64 `mapstructure:"max_exposure_eur"`
+	// MaxSpreadPercent blocks trades on spreads wider than this, which usually mean a
+	// bad quote rather than a real opportunity.
+	MaxSpreadPercent float64 `mapstructure:"max_spread_percent"`
+	// Halted starts the engine with the kill switch engaged: prices and opportunities
+	// are still recorded, but nothing is traded until it is resumed.
+	Halted bool `mapstructure:"halted"`
+}
+
+// EpisodesConfig limits the trades taken from one persistent opportunity. An episode
+// lasts from the tick the books cross until the tick they uncross.
+type EpisodesConfig struct {
+	// MaxExecutions is how many trades one episode may submit. Defaults to 1; a negative
+	// value lifts the limit.
+	MaxExecutions int `mapstructure:"max_executions"`
+}
+
+// SizingConfig replaces the fixed trade volume with a search for the volume that
+// maximizes net profit. Fixed costs favour larger trades, slippage smaller ones.
+type SizingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxTradeVolumeEUR bounds the search. Defaults to the strategy's trade volume.
+	MaxTradeVolumeEUR float64 `mapstructure:"max_trade_volume_eur"`
+	// Samples is the number of volumes evaluated across the range. Defaults to 10.
+	Samples int `mapstructure:"samples"`
+}
+
+// InventoryConfig enables simulation of pre-funded balances on every exchange. Trades
+// are limited by the balances, and no withdrawal fee is charged per trade.
+type InventoryConfig struct {
+	Enabled   bool            `mapstructure:"enabled"`
+	Rebalance RebalanceConfig `mapstructure:"rebalance"`
+	// SnapshotIntervalMS is how often the holdings are marked to market. Defaults to a minute.
+	SnapshotIntervalMS int `mapstructure:"snapshot_interval_ms"`
+}
+
+// RebalanceConfig enables transfers between exchanges when an asset's inventory becomes
+// skewed. Only assets with a configured transfer are rebalanced.
+type RebalanceConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// SkewThreshold is the share (0-1) of an asset's total that one exchange may hold
+	// before funds are moved to the exchange holding the least.
+	SkewThreshold float64 `mapstructure:"skew_threshold"`
+	// Transfers holds the cost of moving each asset, keyed by asset code.
+	Transfers map[string]TransferConfig `mapstructure:"transfers"`
+}
+
+// TransferConfig describes the fee and confirmation delay of moving an asset, e.g. an
+// on-chain withdrawal or a SEPA transfer.
+type TransferConfig struct {
+	Fee     float64 `mapstructure:"fee"` // In units of the asset
+	DelayMS int     `mapstructure:"delay_ms"`
+}
+
+// StrategyConfig configures one strategy instance. Several instances of the same
+// type can run with different parameters as long as their names differ.
+type StrategyConfig struct {
+	Name string `mapstructure:"name"` // Defaults to Type
+	Type string `mapstructure:"type"` // "spatial" or "triangular"
+	// TradeVolumeEUR overrides simulated_trade_volume_eur for this strategy. Like every
+	// EUR amount of a strategy, it is converted at the EUR mid price into the asset a
+	// trade spends: the pair's quote asset, or a cycle's start asset.
+	TradeVolumeEUR float64 `mapstructure:"trade_volume_eur"`
+	// MinNetProfitEUR is the smallest expected net profit worth executing.
+	MinNetProfitEUR float64 `mapstructure:"min_net_profit_eur"`
+	// MaxTradeVolumeEUR overrides sizing.max_trade_volume_eur for this strategy.
+	MaxTradeVolumeEUR float64 `mapstructure:"max_trade_volume_eur"`
+	// StartAsset and Exchanges apply to triangular strategies, see TriangularConfig.
+	StartAsset string   `mapstructure:"start_asset"`
+	Exchanges  []string `mapstructure:"exchanges"`
+}
+
+// TriangularConfig enables detection of three-pair cycles within a single exchange.
+type TriangularConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// StartAsset is the asset every cycle starts and ends in. Defaults to EUR.
+	StartAsset string `mapstructure:"start_asset"`
+	// Exchanges restricts detection to the listed exchanges; empty means all of them.
+	Exchanges []string `mapstructure:"exchanges"`
+}
+
+// Pairs returns the canonical trading pairs to monitor, falling back to the
+// single TradingPair setting of older configurations.
+func (c *ArbitrageConfig) Pairs() []string {
+	if len(c.TradingPairs) > 0 {
+		return c.TradingPairs
+	}
+	if c.TradingPair != "" {
+		return []string{c.TradingPair}
+	}
+	return nil
+}
+
+// StrategyConfigs returns the configured strategies, falling back to the strategies
+// implied by the top-level settings of older configurations.
+func (c *ArbitrageConfig) StrategyConfigs() []StrategyConfig {
+	if len(c.Strategies) > 0 {
+		return c.Strategies
+	}
+	strategies := []StrategyConfig{{Name: "spatial", Type: "spatial"}}
+	if c.Triangular.Enabled {
+		strategies = append(strategies, StrategyConfig{
+			Name:       "triangular",
+			Type:       "triangular",
+			StartAsset: c.Triangular.StartAsset,
+			Exchanges:  c.Triangular.Exchanges,
+		})
+	}
+	return strategies
+}
+
+// InstrumentsConfig defines where instrument metadata is loaded from. Definitions
+// given inline take precedence over those read from File, a JSON array of instruments.
+type InstrumentsConfig struct {
+	File        string             `mapstructure:"file"`
+	Definitions []InstrumentConfig `mapstructure:"definitions"`
+}
+
+// InstrumentConfig describes a canonical trading pair and its per-exchange listings.
+type InstrumentConfig struct {
+	Symbol    string                   `mapstructure:"symbol" json:"symbol"`
+	Base      string                   `mapstructure:"base" json:"base"`
+	Quote     string                   `mapstructure:"quote" json:"quote"`
+	Exchanges map[string]ListingConfig `mapstructure:"exchanges" json:"exchanges"`
+}
+
+// ListingConfig describes how an instrument trades on a specific exchange.
+// An empty Symbol is derived from the exchange's naming convention.
+type ListingConfig struct {
+	Symbol      string  `mapstructure:"symbol" json:"symbol"`
+	TickSize    float64 `mapstructure:"tick_size" json:"tick_size"`
+	LotSize     float64 `mapstructure:"lot_size" json:"lot_size"`
+	MinNotional float64 `mapstructure:"min_notional" json:"min_notional"`
 }
 
 // DatabaseConfig defines the database connection settings.
@@ -39,6 +213,72 @@ func (c *DatabaseConfig) DSN() string {
 // ExchangeConfig defines settings for a specific exchange.
 type ExchangeConfig struct {
 	TakerFeePercent float64 `mapstructure:"taker_fee_percent"`
+	MaxQuoteAgeMS   int     `mapstructure:"max_quote_age_ms"`
+	// Pairs restricts the exchange to a subset of the monitored pairs.
+	Pairs []string `mapstructure:"pairs"`
+	// AssetAliases maps canonical asset codes to the exchange's own codes (e.g. BTC: XBT).
+	AssetAliases map[string]string `mapstructure:"asset_aliases"`
+	// Balances are the starting balances per asset when inventory simulation is enabled.
+	Balances map[string]float64 `mapstructure:"balances"`
+	// FeeSchedule replaces TakerFeePercent with volume-tiered taker fees.
+	FeeSchedule FeeScheduleConfig `mapstructure:"fee_schedule"`
+	// WithdrawalFees are the network fees per asset, in units of the asset, for
+	// withdrawing from the exchange. They replace network_withdrawal_fee_eur.
+	WithdrawalFees map[string]float64 `mapstructure:"withdrawal_fees"`
+}
+
+// FeeScheduleConfig describes an exchange's fees. The tier applied is the one with the
+// highest MinVolumeEUR not above the simulated 30-day trading volume on the exchange;
+// below the lowest tier, TakerFeePercent applies.
+type FeeScheduleConfig struct {
+	Tiers []FeeTierConfig `mapstructure:"tiers"`
+	// DiscountPercent reduces every fee, e.g. 25 for paying Binance fees in BNB.
+	DiscountPercent float64 `mapstructure:"discount_percent"`
+	// Overrides replace the tiers for specific pairs, e.g. promotional zero-fee pairs.
+	Overrides []FeeOverrideConfig `mapstructure:"overrides"`
+}
+
+// FeeTierConfig is one volume tier of a fee schedule. Simulated trades always take
+// liquidity, so only the taker fee is configured.
+type FeeTierConfig struct {
+	MinVolumeEUR    float64 `mapstructure:"min_volume_eur"`
+	TakerFeePercent float64 `mapstructure:"taker_fee_percent"`
+}
+
+// FeeOverrideConfig gives a pair its own fee tiers.
+type FeeOverrideConfig struct {
+	Pair  string          `mapstructure:"pair"`
+	Tiers []FeeTierConfig `mapstructure:"tiers"`
+}
+
+// WithdrawalFee returns the fee, in units of the asset, for withdrawing the asset from
+// the exchange. The second return value is false if no fee is configured.
+func (c *Config) WithdrawalFee(exchange, asset string) (float64, bool) {
+	for configured, fee := range c.Exchanges[exchange].WithdrawalFees {
+		if strings.EqualFold(configured, asset) {
+			return fee, true
+		}
+	}
+	return 0, false
+}
+
+// PairsFor returns the canonical pairs the given exchange should stream.
+func (c *Config) PairsFor(exchange string) []string {
+	if pairs := c.Exchanges[exchange].Pairs; len(pairs) > 0 {
+		return pairs
+	}
+	return c.Arbitrage.Pairs()
+}
+
+// MaxQuoteAge returns how old a quote from the given exchange may be before it is
+// considered stale. The per-exchange setting overrides the arbitrage default; zero
+// disables the check.
+func (c *Config) MaxQuoteAge(exchange string) time.Duration {
+	ms := c.Arbitrage.MaxQuoteAgeMS
+	if exchangeMS := c.Exchanges[exchange].MaxQuoteAgeMS; exchangeMS > 0 {
+		ms = exchangeMS
+	}
+	return time.Duration(ms) * time.Millisecond
 }
 
 // LoadConfig reads configuration from file or environment variables.
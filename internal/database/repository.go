@@ -4,7 +4,9 @@ import (
 	"context"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"referee/internal/clock"
 	"referee/internal/model"
 )
 
@@ -12,12 +14,41 @@ import (
 type Repository interface {
 	LogTrade(ctx context.Context, trade model.SimulatedTrade) error
 	LogPriceTick(ctx context.Context, tick model.PriceTick) error
+	LogBalances(ctx context.Context, balances []model.Balance) error
+	LogTransfer(ctx context.Context, transfer model.Transfer) error
+	LogLedgerEntry(ctx context.Context, entry model.LedgerEntry) error
+	LogEquitySnapshot(ctx context.Context, snapshot model.EquitySnapshot) error
+	LogOpportunity(ctx context.Context, opportunity model.Opportunity) error
+	LogEpisode(ctx context.Context, episode model.Episode) error
+	LogRiskEvent(ctx context.Context, event model.RiskEvent) error
 	Migrate(ctx context.Context) error
 }
 
 // PostgresRepository is the PostgreSQL implementation of the Repository.
+// Clock stamps inserted rows; the system clock is used if it is nil. RunID identifies
+// the process writing the rows, since ledger entry and episode IDs are only unique
+// within one run; StartRun assigns it, and rows written without one have no run.
 type PostgresRepository struct {
-	Pool *pgxpool.Pool
+	Pool  *pgxpool.Pool
+	Clock clock.Clock
+	RunID int64
+}
+
+// now returns the current time according to the repository clock.
+func (r *PostgresRepository) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
+// StartRun registers a new run and scopes the rows inserted from now on to it.
+func (r *PostgresRepository) StartRun(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := `INSERT INTO runs (started_at) VALUES ($1) RETURNING id`
+	return r.Pool.QueryRow(ctx, query, r.now()).Scan(&r.RunID)
 }
 
 // LogPriceTick inserts a new price tick into the database.
@@ -25,11 +56,197 @@ func (r *PostgresRepository) LogPriceTick(ctx context.Context, tick model.PriceT
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	query := `INSERT INTO price_ticks (timestamp, exchange, pair, bid, ask) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.Pool.Exec(ctx, query, time.Now(), tick.Exchange, tick.Pair, tick.Bid, tick.Ask)
+	query := `
+		INSERT INTO price_ticks (
+			timestamp, exchange, pair, bid, ask, exchange_timestamp, received_timestamp
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.Pool.Exec(ctx, query,
+		r.now(),
+		tick.Exchange,
+		tick.Pair,
+		tick.Bid,
+		tick.Ask,
+		nullTime(tick.ExchangeTime),
+		nullTime(tick.ReceivedAt),
+	)
+	return err
+}
+
+// LogBalances inserts a snapshot of exchange balances into the database.
+func (r *PostgresRepository) LogBalances(ctx context.Context, balances []model.Balance) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO balances (timestamp, exchange, asset, amount)
+		VALUES ($1, $2, $3, $4)`
+	batch := &pgx.Batch{}
+	for _, balance := range balances {
+		batch.Queue(query, balance.Timestamp, balance.Exchange, balance.Asset, balance.Amount)
+	}
+	return r.Pool.SendBatch(ctx, batch).Close()
+}
+
+// LogTransfer inserts a simulated transfer between exchanges into the database.
+func (r *PostgresRepository) LogTransfer(ctx context.Context, transfer model.Transfer) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO transfers (
+			initiated_at, arrives_at, asset, from_exchange, to_exchange, amount, fee, fee_eur
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.Pool.Exec(ctx, query,
+		transfer.InitiatedAt,
+		transfer.ArrivesAt,
+		transfer.Asset,
+		transfer.FromExchange,
+		transfer.ToExchange,
+		transfer.Amount,
+		transfer.Fee,
+		transfer.FeeEUR,
+	)
+	return err
+}
+
+// LogLedgerEntry inserts every posting of a ledger entry into the database.
+func (r *PostgresRepository) LogLedgerEntry(ctx context.Context, entry model.LedgerEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO ledger_entries (run_id, entry_id, timestamp, kind, reference, account, asset, amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	batch := &pgx.Batch{}
+	for _, posting := range entry.Postings {
+		batch.Queue(query, r.runID(), entry.ID, entry.Timestamp, entry.Kind, entry.Reference, posting.Account, posting.Asset, posting.Amount)
+	}
+	return r.Pool.SendBatch(ctx, batch).Close()
+}
+
+// LogEquitySnapshot inserts a mark-to-market valuation into the database.
+func (r *PostgresRepository) LogEquitySnapshot(ctx context.Context, snapshot model.EquitySnapshot) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO equity_snapshots (
+			timestamp, equity_eur, realized_pnl_eur, unrealized_pnl_eur, peak_equity_eur,
+			drawdown_eur, max_drawdown_eur
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.Pool.Exec(ctx, query,
+		snapshot.Timestamp,
+		snapshot.EquityEUR,
+		snapshot.RealizedPnLEUR,
+		snapshot.UnrealizedPnLEUR,
+		snapshot.PeakEquityEUR,
+		snapshot.DrawdownEUR,
+		snapshot.MaxDrawdownEUR,
+	)
+	return err
+}
+
+// LogOpportunity inserts a detected opportunity and its outcome into the database.
+func (r *PostgresRepository) LogOpportunity(ctx context.Context, opportunity model.Opportunity) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO opportunities (
+			timestamp, strategy, trade_type, trading_pair, route, buy_exchange, sell_exchange,
+			buy_price, sell_price, volume_eur, gross_profit_eur, total_fees_eur, net_profit_eur, reason,
+			episode_id, run_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+	_, err := r.Pool.Exec(ctx, query,
+		opportunity.Timestamp,
+		opportunity.Strategy,
+		opportunity.TradeType,
+		opportunity.TradingPair,
+		opportunity.Route,
+		opportunity.BuyExchange,
+		opportunity.SellExchange,
+		opportunity.BuyPrice,
+		opportunity.SellPrice,
+		opportunity.VolumeEUR,
+		opportunity.GrossProfitEUR,
+		opportunity.TotalFeesEUR,
+		opportunity.NetProfitEUR,
+		opportunity.Reason,
+		opportunity.EpisodeID,
+		r.runID(),
+	)
+	return err
+}
+
+// LogEpisode inserts an ended opportunity episode into the database.
+func (r *PostgresRepository) LogEpisode(ctx context.Context, episode model.Episode) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO episodes (
+			episode_id, strategy, trade_type, trading_pair, route, buy_exchange, sell_exchange,
+			started_at, ended_at, duration_ms, ticks, peak_spread_percent, peak_net_profit_eur, executions,
+			run_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
+	_, err := r.Pool.Exec(ctx, query,
+		episode.ID,
+		episode.Strategy,
+		episode.TradeType,
+		episode.TradingPair,
+		episode.Route,
+		episode.BuyExchange,
+		episode.SellExchange,
+		episode.StartedAt,
+		episode.EndedAt,
+		episode.Duration().Milliseconds(),
+		episode.Ticks,
+		episode.PeakSpreadPercent,
+		episode.PeakNetProfitEUR,
+		episode.Executions,
+		r.runID(),
+	)
+	return err
+}
+
+// LogRiskEvent inserts a blocked trade or kill switch change into the database.
+func (r *PostgresRepository) LogRiskEvent(ctx context.Context, event model.RiskEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	query := `
+		INSERT INTO risk_events (
+			timestamp, rule, action, strategy, trading_pair, buy_exchange, sell_exchange, detail
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.Pool.Exec(ctx, query,
+		event.Timestamp,
+		event.Rule,
+		event.Action,
+		event.Strategy,
+		event.TradingPair,
+		event.BuyExchange,
+		event.SellExchange,
+		event.Detail,
+	)
 	return err
 }
 
+// runID returns the run rows are scoped to, or SQL NULL if no run was started.
+func (r *PostgresRepository) runID() *int64 {
+	if r.RunID == 0 {
+		return nil
+	}
+	return &r.RunID
+}
+
+// nullTime maps the zero time to SQL NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
 // LogTrade inserts a new simulated trade into the database.
 func (r *PostgresRepository) LogTrade(ctx context.Context, trade model.SimulatedTrade) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -38,8 +255,12 @@ func (r *PostgresRepository) LogTrade(ctx context.Context, trade model.Simulated
 	query := `
 		INSERT INTO simulated_trades (
 			timestamp, trading_pair, buy_exchange, sell_exchange, buy_price,
-			sell_price, volume_eur, gross_profit_eur, total_fees_eur, net_profit_eur
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+			sell_price, volume_eur, gross_profit_eur, total_fees_eur, net_profit_eur,
+			buy_vwap, sell_vwap, buy_levels_consumed, sell_levels_consumed, slippage_eur,
+			detected_at, detected_buy_price, detected_sell_price, status, trade_type,
+			route, strategy, strategy_params, withdrawal_fee_eur, profit_curve, episode_id, run_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+			$21, $22, $23, $24, $25, $26, $27)`
 
 	_, err := r.Pool.Exec(ctx, query,
 		trade.Timestamp,
@@ -52,6 +273,23 @@ func (r *PostgresRepository) LogTrade(ctx context.Context, trade model.Simulated
 		trade.GrossProfitEUR,
 		trade.TotalFeesEUR,
 		trade.NetProfitEUR,
+		trade.BuyVWAP,
+		trade.SellVWAP,
+		trade.BuyLevelsConsumed,
+		trade.SellLevelsConsumed,
+		trade.SlippageEUR,
+		trade.DetectedAt,
+		trade.DetectedBuyPrice,
+		trade.DetectedSellPrice,
+		trade.Status,
+		trade.TradeType,
+		trade.Route,
+		trade.Strategy,
+		trade.StrategyParams,
+		trade.WithdrawalFeeEUR,
+		trade.ProfitCurve,
+		trade.EpisodeID,
+		r.runID(),
 	)
 
 	return err
@@ -62,6 +300,16 @@ func (r *PostgresRepository) Migrate(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
+	// Create runs table, which scopes per-process IDs
+	runsTableQuery := `
+		CREATE TABLE IF NOT EXISTS runs (
+			id SERIAL PRIMARY KEY,
+			started_at TIMESTAMPTZ NOT NULL
+		);`
+	if _, err := r.Pool.Exec(ctx, runsTableQuery); err != nil {
+		return err
+	}
+
 	// Create simulated_trades table
 	tradesTableQuery := `
 		CREATE TABLE IF NOT EXISTS simulated_trades (
@@ -95,5 +343,164 @@ func (r *PostgresRepository) Migrate(ctx context.Context) error {
 		return err
 	}
 
+	// Create balances table
+	balancesTableQuery := `
+		CREATE TABLE IF NOT EXISTS balances (
+			id SERIAL PRIMARY KEY,
+			timestamp TIMESTAMPTZ NOT NULL,
+			exchange VARCHAR(50) NOT NULL,
+			asset VARCHAR(20) NOT NULL,
+			amount NUMERIC(30, 12) NOT NULL
+		);`
+	if _, err := r.Pool.Exec(ctx, balancesTableQuery); err != nil {
+		return err
+	}
+
+	// Create transfers table
+	transfersTableQuery := `
+		CREATE TABLE IF NOT EXISTS transfers (
+			id SERIAL PRIMARY KEY,
+			initiated_at TIMESTAMPTZ NOT NULL,
+			arrives_at TIMESTAMPTZ NOT NULL,
+			asset VARCHAR(20) NOT NULL,
+			from_exchange VARCHAR(50) NOT NULL,
+			to_exchange VARCHAR(50) NOT NULL,
+			amount NUMERIC(30, 12) NOT NULL,
+			fee NUMERIC(30, 12) NOT NULL,
+			fee_eur NUMERIC(20, 8) NOT NULL
+		);`
+	if _, err := r.Pool.Exec(ctx, transfersTableQuery); err != nil {
+		return err
+	}
+
+	// Create ledger_entries table, one row per posting
+	ledgerTableQuery := `
+		CREATE TABLE IF NOT EXISTS ledger_entries (
+			id SERIAL PRIMARY KEY,
+			entry_id BIGINT NOT NULL,
+			timestamp TIMESTAMPTZ NOT NULL,
+			kind VARCHAR(20) NOT NULL,
+			reference VARCHAR(200) NOT NULL,
+			account VARCHAR(100) NOT NULL,
+			asset VARCHAR(20) NOT NULL,
+			amount NUMERIC(30, 12) NOT NULL
+		);`
+	if _, err := r.Pool.Exec(ctx, ledgerTableQuery); err != nil {
+		return err
+	}
+
+	// Create equity_snapshots table
+	equityTableQuery := `
+		CREATE TABLE IF NOT EXISTS equity_snapshots (
+			id SERIAL PRIMARY KEY,
+			timestamp TIMESTAMPTZ NOT NULL,
+			equity_eur NUMERIC(20, 8) NOT NULL,
+			realized_pnl_eur NUMERIC(20, 8) NOT NULL,
+			unrealized_pnl_eur NUMERIC(20, 8) NOT NULL,
+			peak_equity_eur NUMERIC(20, 8) NOT NULL,
+			drawdown_eur NUMERIC(20, 8) NOT NULL,
+			max_drawdown_eur NUMERIC(20, 8) NOT NULL
+		);`
+	if _, err := r.Pool.Exec(ctx, equityTableQuery); err != nil {
+		return err
+	}
+
+	// Create opportunities table
+	opportunitiesTableQuery := `
+		CREATE TABLE IF NOT EXISTS opportunities (
+			id SERIAL PRIMARY KEY,
+			timestamp TIMESTAMPTZ NOT NULL,
+			strategy VARCHAR(50) NOT NULL,
+			trade_type VARCHAR(20) NOT NULL,
+			trading_pair VARCHAR(20) NOT NULL,
+			route VARCHAR(100) NOT NULL,
+			buy_exchange VARCHAR(50) NOT NULL,
+			sell_exchange VARCHAR(50) NOT NULL,
+			buy_price NUMERIC(20, 8) NOT NULL,
+			sell_price NUMERIC(20, 8) NOT NULL,
+			volume_eur NUMERIC(20, 8) NOT NULL,
+			gross_profit_eur NUMERIC(20, 8) NOT NULL,
+			total_fees_eur NUMERIC(20, 8) NOT NULL,
+			net_profit_eur NUMERIC(20, 8) NOT NULL,
+			reason VARCHAR(30) NOT NULL
+		);`
+	if _, err := r.Pool.Exec(ctx, opportunitiesTableQuery); err != nil {
+		return err
+	}
+
+	// Create episodes table
+	episodesTableQuery := `
+		CREATE TABLE IF NOT EXISTS episodes (
+			id SERIAL PRIMARY KEY,
+			episode_id BIGINT NOT NULL,
+			strategy VARCHAR(50) NOT NULL,
+			trade_type VARCHAR(20) NOT NULL,
+			trading_pair VARCHAR(20) NOT NULL,
+			route VARCHAR(100) NOT NULL,
+			buy_exchange VARCHAR(50) NOT NULL,
+			sell_exchange VARCHAR(50) NOT NULL,
+			started_at TIMESTAMPTZ NOT NULL,
+			ended_at TIMESTAMPTZ NOT NULL,
+			duration_ms BIGINT NOT NULL,
+			ticks INTEGER NOT NULL,
+			peak_spread_percent NUMERIC(20, 8) NOT NULL,
+			peak_net_profit_eur NUMERIC(20, 8) NOT NULL,
+			executions INTEGER NOT NULL
+		);`
+	if _, err := r.Pool.Exec(ctx, episodesTableQuery); err != nil {
+		return err
+	}
+
+	// Create risk events table
+	riskEventsTableQuery := `
+		CREATE TABLE IF NOT EXISTS risk_events (
+			id SERIAL PRIMARY KEY,
+			timestamp TIMESTAMPTZ NOT NULL,
+			rule VARCHAR(30) NOT NULL,
+			action VARCHAR(20) NOT NULL,
+			strategy VARCHAR(50) NOT NULL,
+			trading_pair VARCHAR(20) NOT NULL,
+			buy_exchange VARCHAR(50) NOT NULL,
+			sell_exchange VARCHAR(50) NOT NULL,
+			detail TEXT NOT NULL
+		);`
+	if _, err := r.Pool.Exec(ctx, riskEventsTableQuery); err != nil {
+		return err
+	}
+
+	// Add columns introduced after the initial schema
+	for _, query := range columnMigrations {
+		if _, err := r.Pool.Exec(ctx, query); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// columnMigrations upgrade existing tables in place. Each statement must be idempotent.
+var columnMigrations = []string{
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS buy_vwap NUMERIC(20, 8) NOT NULL DEFAULT 0`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS sell_vwap NUMERIC(20, 8) NOT NULL DEFAULT 0`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS buy_levels_consumed INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS sell_levels_consumed INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS slippage_eur NUMERIC(20, 8) NOT NULL DEFAULT 0`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS detected_at TIMESTAMPTZ`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS detected_buy_price NUMERIC(20, 8) NOT NULL DEFAULT 0`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS detected_sell_price NUMERIC(20, 8) NOT NULL DEFAULT 0`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'executed'`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS trade_type VARCHAR(20) NOT NULL DEFAULT 'spatial'`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS route VARCHAR(100) NOT NULL DEFAULT ''`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS strategy VARCHAR(50) NOT NULL DEFAULT ''`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS strategy_params TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS withdrawal_fee_eur NUMERIC(20, 8) NOT NULL DEFAULT 0`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS profit_curve JSONB`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS episode_id BIGINT`,
+	`ALTER TABLE opportunities ADD COLUMN IF NOT EXISTS episode_id BIGINT`,
+	`ALTER TABLE price_ticks ADD COLUMN IF NOT EXISTS exchange_timestamp TIMESTAMPTZ`,
+	`ALTER TABLE price_ticks ADD COLUMN IF NOT EXISTS received_timestamp TIMESTAMPTZ`,
+	`ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS run_id BIGINT`,
+	`ALTER TABLE episodes ADD COLUMN IF NOT EXISTS run_id BIGINT`,
+	`ALTER TABLE opportunities ADD COLUMN IF NOT EXISTS run_id BIGINT`,
+	`ALTER TABLE simulated_trades ADD COLUMN IF NOT EXISTS run_id BIGINT`,
+}
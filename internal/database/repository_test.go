@@ -67,24 +67,9 @@ func TestMain(m *testing.M) {
 	}
 	defer pool.Close()
 
-	// Create the table
-	createTableSQL := `
-	CREATE TABLE simulated_trades (
-		id SERIAL PRIMARY KEY,
-		timestamp TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-		trading_pair VARCHAR(20) NOT NULL,
-		buy_exchange VARCHAR(50) NOT NULL,
-		sell_exchange VARCHAR(50) NOT NULL,
-		buy_price NUMERIC(20, 8) NOT NULL,
-		sell_price NUMERIC(20, 8) NOT NULL,
-		volume_eur NUMERIC(20, 8) NOT NULL,
-		gross_profit_eur NUMERIC(20, 8) NOT NULL,
-		total_fees_eur NUMERIC(20, 8) NOT NULL,
-		net_profit_eur NUMERIC(20, 8) NOT NULL
-	);`
-	_, err = pool.Exec(ctx, createTableSQL)
-	if err != nil {
-		log.Fatalf("could not create table: %s", err)
+	// Create the tables
+	if err := (&PostgresRepository{Pool: pool}).Migrate(ctx); err != nil {
+		log.Fatalf("could not migrate database: %s", err)
 	}
 
 	// Run the tests
@@ -98,16 +83,33 @@ func TestPostgresRepository_LogTrade(t *testing.T) {
 	repo := &PostgresRepository{Pool: pool}
 
 	trade := model.SimulatedTrade{
-		Timestamp:      time.Now(),
-		TradingPair:    "BTC/EUR",
-		BuyExchange:    "kraken",
-		SellExchange:   "binance",
-		BuyPrice:       60000.0,
-		SellPrice:      60100.0,
-		VolumeEUR:      1000.0,
-		GrossProfitEUR: 1.66666667,
-		TotalFeesEUR:   1.86,
-		NetProfitEUR:   -0.19333333,
+		Timestamp:          time.Now(),
+		TradingPair:        "BTC/EUR",
+		BuyExchange:        "kraken",
+		SellExchange:       "binance",
+		BuyPrice:           60000.0,
+		SellPrice:          60100.0,
+		VolumeEUR:          1000.0,
+		GrossProfitEUR:     1.66666667,
+		TotalFeesEUR:       1.86,
+		NetProfitEUR:       -0.19333333,
+		BuyVWAP:            60012.5,
+		SellVWAP:           60095.0,
+		BuyLevelsConsumed:  2,
+		SellLevelsConsumed: 1,
+		SlippageEUR:        0.29166667,
+		DetectedAt:         time.Now().Add(-50 * time.Millisecond),
+		DetectedBuyPrice:   60000.0,
+		DetectedSellPrice:  60150.0,
+		Status:             "executed",
+		TradeType:          "spatial",
+		Strategy:           "spatial-large",
+		StrategyParams:     "trade_volume_eur=1000 min_net_profit_eur=0",
+		WithdrawalFeeEUR:   1.2,
+		ProfitCurve: []model.ProfitSample{
+			{VolumeEUR: 500, NetProfitEUR: -0.5},
+			{VolumeEUR: 1000, NetProfitEUR: -0.19333333},
+		},
 	}
 
 	err := repo.LogTrade(ctx, trade)
@@ -122,4 +124,300 @@ func TestPostgresRepository_LogTrade(t *testing.T) {
 	assert.Equal(t, trade.TradingPair, loggedTrade.TradingPair)
 	assert.Equal(t, trade.BuyExchange, loggedTrade.BuyExchange)
 	assert.Equal(t, trade.SellExchange, loggedTrade.SellExchange)
+
+	// Verify the order book impact was logged
+	err = pool.QueryRow(ctx, "SELECT buy_vwap, sell_vwap, buy_levels_consumed, sell_levels_consumed, slippage_eur FROM simulated_trades WHERE buy_exchange = 'kraken'").Scan(
+		&loggedTrade.BuyVWAP, &loggedTrade.SellVWAP, &loggedTrade.BuyLevelsConsumed, &loggedTrade.SellLevelsConsumed, &loggedTrade.SlippageEUR,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, trade.BuyVWAP, loggedTrade.BuyVWAP)
+	assert.Equal(t, trade.BuyLevelsConsumed, loggedTrade.BuyLevelsConsumed)
+	assert.Equal(t, trade.SellLevelsConsumed, loggedTrade.SellLevelsConsumed)
+
+	// Verify the detected prices and status were logged
+	err = pool.QueryRow(ctx, "SELECT detected_buy_price, detected_sell_price, status FROM simulated_trades WHERE buy_exchange = 'kraken'").Scan(
+		&loggedTrade.DetectedBuyPrice, &loggedTrade.DetectedSellPrice, &loggedTrade.Status,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, trade.DetectedSellPrice, loggedTrade.DetectedSellPrice)
+	assert.Equal(t, trade.Status, loggedTrade.Status)
+
+	// Verify the trade type and route were logged
+	err = pool.QueryRow(ctx, "SELECT trade_type, route FROM simulated_trades WHERE buy_exchange = 'kraken'").Scan(
+		&loggedTrade.TradeType, &loggedTrade.Route,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, trade.TradeType, loggedTrade.TradeType)
+	assert.Equal(t, trade.Route, loggedTrade.Route)
+
+	// Verify the strategy was logged
+	err = pool.QueryRow(ctx, "SELECT strategy, strategy_params FROM simulated_trades WHERE buy_exchange = 'kraken'").Scan(
+		&loggedTrade.Strategy, &loggedTrade.StrategyParams,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, trade.Strategy, loggedTrade.Strategy)
+	assert.Equal(t, trade.StrategyParams, loggedTrade.StrategyParams)
+
+	// Verify the withdrawal fee was logged
+	err = pool.QueryRow(ctx, "SELECT withdrawal_fee_eur FROM simulated_trades WHERE buy_exchange = 'kraken'").Scan(&loggedTrade.WithdrawalFeeEUR)
+	assert.NoError(t, err)
+	assert.Equal(t, trade.WithdrawalFeeEUR, loggedTrade.WithdrawalFeeEUR)
+
+	// Verify the profit curve was logged
+	err = pool.QueryRow(ctx, "SELECT profit_curve FROM simulated_trades WHERE buy_exchange = 'kraken'").Scan(&loggedTrade.ProfitCurve)
+	assert.NoError(t, err)
+	assert.Equal(t, trade.ProfitCurve, loggedTrade.ProfitCurve)
+}
+
+func TestPostgresRepository_LogPriceTick(t *testing.T) {
+	ctx := context.Background()
+	repo := &PostgresRepository{Pool: pool}
+
+	exchangeTime := time.Now().Add(-120 * time.Millisecond).UTC().Truncate(time.Microsecond)
+	receivedAt := time.Now().UTC().Truncate(time.Microsecond)
+	tick := model.PriceTick{
+		Exchange:     "binance",
+		Pair:         "BTC/EUR",
+		Bid:          60000.0,
+		Ask:          60010.0,
+		ExchangeTime: exchangeTime,
+		ReceivedAt:   receivedAt,
+	}
+
+	err := repo.LogPriceTick(ctx, tick)
+	assert.NoError(t, err)
+
+	// Verify both timestamps were stored
+	var loggedExchangeTime, loggedReceivedAt time.Time
+	err = pool.QueryRow(ctx, "SELECT exchange_timestamp, received_timestamp FROM price_ticks WHERE exchange = 'binance'").Scan(
+		&loggedExchangeTime, &loggedReceivedAt,
+	)
+	assert.NoError(t, err)
+	assert.True(t, exchangeTime.Equal(loggedExchangeTime))
+	assert.True(t, receivedAt.Equal(loggedReceivedAt))
+}
+
+func TestPostgresRepository_LogBalances(t *testing.T) {
+	ctx := context.Background()
+	repo := &PostgresRepository{Pool: pool}
+
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	balances := []model.Balance{
+		{Timestamp: at, Exchange: "kraken", Asset: "BTC", Amount: 0.15},
+		{Timestamp: at, Exchange: "kraken", Asset: "EUR", Amount: 6992.2},
+	}
+
+	err := repo.LogBalances(ctx, balances)
+	assert.NoError(t, err)
+
+	// Verify every balance of the snapshot was stored
+	var count int
+	var amount float64
+	err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM balances WHERE exchange = 'kraken'").Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	err = pool.QueryRow(ctx, "SELECT amount FROM balances WHERE exchange = 'kraken' AND asset = 'EUR'").Scan(&amount)
+	assert.NoError(t, err)
+	assert.Equal(t, 6992.2, amount)
+}
+
+func TestPostgresRepository_LogTransfer(t *testing.T) {
+	ctx := context.Background()
+	repo := &PostgresRepository{Pool: pool}
+
+	initiatedAt := time.Now().UTC().Truncate(time.Microsecond)
+	transfer := model.Transfer{
+		InitiatedAt:  initiatedAt,
+		ArrivesAt:    initiatedAt.Add(30 * time.Minute),
+		Asset:        "BTC",
+		FromExchange: "binance",
+		ToExchange:   "kraken",
+		Amount:       0.05,
+		Fee:          0.0002,
+		FeeEUR:       12.0,
+	}
+
+	err := repo.LogTransfer(ctx, transfer)
+	assert.NoError(t, err)
+
+	// Verify the transfer and its cost were logged
+	var logged model.Transfer
+	err = pool.QueryRow(ctx, "SELECT arrives_at, asset, to_exchange, amount, fee_eur FROM transfers WHERE from_exchange = 'binance'").Scan(
+		&logged.ArrivesAt, &logged.Asset, &logged.ToExchange, &logged.Amount, &logged.FeeEUR,
+	)
+	assert.NoError(t, err)
+	assert.True(t, transfer.ArrivesAt.Equal(logged.ArrivesAt))
+	assert.Equal(t, transfer.Asset, logged.Asset)
+	assert.Equal(t, transfer.ToExchange, logged.ToExchange)
+	assert.Equal(t, transfer.Amount, logged.Amount)
+	assert.Equal(t, transfer.FeeEUR, logged.FeeEUR)
+}
+
+func TestPostgresRepository_LogLedgerEntry(t *testing.T) {
+	ctx := context.Background()
+	repo := &PostgresRepository{Pool: pool}
+	assert.NoError(t, repo.StartRun(ctx))
+
+	entry := model.LedgerEntry{
+		ID:        42,
+		Timestamp: time.Now().UTC().Truncate(time.Microsecond),
+		Kind:      "transfer_out",
+		Reference: "BTC kraken>binance",
+		Postings: []model.Posting{
+			{Account: "assets:kraken", Asset: "BTC", Amount: -0.05},
+			{Account: "assets:in_transit", Asset: "BTC", Amount: 0.05},
+		},
+	}
+
+	err := repo.LogLedgerEntry(ctx, entry)
+	assert.NoError(t, err)
+
+	// Verify every posting was stored and the entry balances
+	var count int
+	var total float64
+	err = pool.QueryRow(ctx, "SELECT COUNT(*), SUM(amount) FROM ledger_entries WHERE entry_id = 42").Scan(&count, &total)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 0.0, total)
+
+	// A later run reusing the entry ID is told apart by its run ID
+	other := &PostgresRepository{Pool: pool}
+	assert.NoError(t, other.StartRun(ctx))
+	assert.NotEqual(t, repo.RunID, other.RunID)
+	assert.NoError(t, other.LogLedgerEntry(ctx, entry))
+	err = pool.QueryRow(ctx, "SELECT COUNT(*) FROM ledger_entries WHERE entry_id = 42 AND run_id = $1", repo.RunID).Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestPostgresRepository_LogEquitySnapshot(t *testing.T) {
+	ctx := context.Background()
+	repo := &PostgresRepository{Pool: pool}
+
+	snapshot := model.EquitySnapshot{
+		Timestamp:        time.Now().UTC().Truncate(time.Microsecond),
+		EquityEUR:        14995,
+		RealizedPnLEUR:   -15,
+		UnrealizedPnLEUR: 5,
+		PeakEquityEUR:    16045,
+		DrawdownEUR:      1050,
+		MaxDrawdownEUR:   1050,
+	}
+
+	err := repo.LogEquitySnapshot(ctx, snapshot)
+	assert.NoError(t, err)
+
+	// Verify the valuation was logged
+	var logged model.EquitySnapshot
+	err = pool.QueryRow(ctx, "SELECT equity_eur, realized_pnl_eur, max_drawdown_eur FROM equity_snapshots").Scan(
+		&logged.EquityEUR, &logged.RealizedPnLEUR, &logged.MaxDrawdownEUR,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot.EquityEUR, logged.EquityEUR)
+	assert.Equal(t, snapshot.RealizedPnLEUR, logged.RealizedPnLEUR)
+	assert.Equal(t, snapshot.MaxDrawdownEUR, logged.MaxDrawdownEUR)
+}
+
+func TestPostgresRepository_LogOpportunity(t *testing.T) {
+	ctx := context.Background()
+	repo := &PostgresRepository{Pool: pool}
+
+	opportunity := model.Opportunity{
+		Timestamp:      time.Now().UTC().Truncate(time.Microsecond),
+		Strategy:       "spatial",
+		TradeType:      "spatial",
+		TradingPair:    "BTC/EUR",
+		BuyExchange:    "kraken",
+		SellExchange:   "binance",
+		BuyPrice:       60000,
+		SellPrice:      60050,
+		VolumeEUR:      1000,
+		GrossProfitEUR: 0.83333333,
+		TotalFeesEUR:   8.6,
+		NetProfitEUR:   -7.76666667,
+		Reason:         "unprofitable",
+	}
+
+	err := repo.LogOpportunity(ctx, opportunity)
+	assert.NoError(t, err)
+
+	// Verify the opportunity was logged
+	var logged model.Opportunity
+	err = pool.QueryRow(ctx, "SELECT trading_pair, gross_profit_eur, net_profit_eur, reason FROM opportunities").Scan(
+		&logged.TradingPair, &logged.GrossProfitEUR, &logged.NetProfitEUR, &logged.Reason,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, opportunity.TradingPair, logged.TradingPair)
+	assert.Equal(t, opportunity.NetProfitEUR, logged.NetProfitEUR)
+	assert.Equal(t, opportunity.Reason, logged.Reason)
+}
+
+func TestPostgresRepository_LogEpisode(t *testing.T) {
+	ctx := context.Background()
+	repo := &PostgresRepository{Pool: pool}
+	assert.NoError(t, repo.StartRun(ctx))
+
+	startedAt := time.Now().UTC().Truncate(time.Microsecond)
+	episode := model.Episode{
+		ID:                7,
+		Strategy:          "spatial",
+		TradeType:         "spatial",
+		TradingPair:       "BTC/EUR",
+		BuyExchange:       "kraken",
+		SellExchange:      "binance",
+		StartedAt:         startedAt,
+		EndedAt:           startedAt.Add(1500 * time.Millisecond),
+		Ticks:             12,
+		PeakSpreadPercent: 1.5,
+		PeakNetProfitEUR:  8.05,
+		Executions:        1,
+	}
+
+	err := repo.LogEpisode(ctx, episode)
+	assert.NoError(t, err)
+
+	// Verify the episode was logged with its duration
+	var durationMS int64
+	var logged model.Episode
+	var runID int64
+	err = pool.QueryRow(ctx, "SELECT episode_id, duration_ms, ticks, peak_spread_percent, executions, run_id FROM episodes").Scan(
+		&logged.ID, &durationMS, &logged.Ticks, &logged.PeakSpreadPercent, &logged.Executions, &runID,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.RunID, runID)
+	assert.Equal(t, episode.ID, logged.ID)
+	assert.Equal(t, int64(1500), durationMS)
+	assert.Equal(t, episode.Ticks, logged.Ticks)
+	assert.Equal(t, episode.PeakSpreadPercent, logged.PeakSpreadPercent)
+	assert.Equal(t, episode.Executions, logged.Executions)
+}
+
+func TestPostgresRepository_LogRiskEvent(t *testing.T) {
+	ctx := context.Background()
+	repo := &PostgresRepository{Pool: pool}
+
+	event := model.RiskEvent{
+		Timestamp:    time.Now().UTC().Truncate(time.Microsecond),
+		Rule:         "exposure",
+		Action:       "blocked",
+		Strategy:     "spatial",
+		TradingPair:  "BTC/EUR",
+		BuyExchange:  "kraken",
+		SellExchange: "binance",
+		Detail:       "kraken exposure would reach 2000.00 EUR, limit is 1500 EUR",
+	}
+
+	err := repo.LogRiskEvent(ctx, event)
+	assert.NoError(t, err)
+
+	// Verify the event was logged
+	var logged model.RiskEvent
+	err = pool.QueryRow(ctx, "SELECT rule, action, buy_exchange, detail FROM risk_events").Scan(
+		&logged.Rule, &logged.Action, &logged.BuyExchange, &logged.Detail,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, event.Rule, logged.Rule)
+	assert.Equal(t, event.Action, logged.Action)
+	assert.Equal(t, event.BuyExchange, logged.BuyExchange)
+	assert.Equal(t, event.Detail, logged.Detail)
 }
@@ -3,31 +3,76 @@ package exchange
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
-	"strconv"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"referee/internal/clock"
+	"referee/internal/instrument"
 	"referee/internal/model"
 )
 
 // BinanceClient implements the ExchangeClient interface for Binance.
 type BinanceClient struct {
-	logger *slog.Logger
+	logger      *slog.Logger
+	clock       clock.Clock
+	instruments *instrument.Registry
+	httpClient  *http.Client
 }
 
 // NewBinanceClient creates a new BinanceClient.
-func NewBinanceClient(logger *slog.Logger) *BinanceClient {
-	return &BinanceClient{logger: logger}
+func NewBinanceClient(logger *slog.Logger, clk clock.Clock, instruments *instrument.Registry) *BinanceClient {
+	return &BinanceClient{
+		logger:      logger,
+		clock:       clk,
+		instruments: instruments,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
 }
 
 func (b *BinanceClient) GetName() string {
 	return "binance"
 }
 
-// StartStream connects to the Binance WebSocket API and streams BTC/EUR price ticks.
-func (b *BinanceClient) StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pair string) error {
-	const wsURL = "wss://stream.binance.com:9443/ws/btceur@ticker"
+// binanceStreamMessage wraps every event received on a combined stream.
+type binanceStreamMessage struct {
+	Stream string             `json:"stream"`
+	Data   binanceDepthUpdate `json:"data"`
+}
+
+// binanceDepthUpdate is a single event of the Binance diff depth stream.
+type binanceDepthUpdate struct {
+	EventTime     int64         `json:"E"`
+	Symbol        string        `json:"s"`
+	FirstUpdateID int64         `json:"U"`
+	FinalUpdateID int64         `json:"u"`
+	Bids          []interface{} `json:"b"`
+	Asks          []interface{} `json:"a"`
+}
+
+// binanceDepthSnapshot is the REST order book snapshot used to seed the local book.
+type binanceDepthSnapshot struct {
+	LastUpdateID int64         `json:"lastUpdateId"`
+	Bids         []interface{} `json:"bids"`
+	Asks         []interface{} `json:"asks"`
+}
+
+// StartStream connects to the Binance WebSocket API and streams order book ticks for all pairs.
+// Each local book is seeded from a REST snapshot and kept in sync with the diff depth
+// stream; a gap in update IDs forces a reconnect and fresh snapshots.
+func (b *BinanceClient) StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pairs []string) error {
+	symbols, err := newSymbolMap(b.instruments, b.GetName(), pairs)
+	if err != nil {
+		return err
+	}
+	streams := make([]string, 0, len(pairs))
+	for _, native := range symbols.Natives() {
+		streams = append(streams, strings.ToLower(native)+"@depth@100ms")
+	}
+	wsURL := "wss://stream.binance.com:9443/stream?streams=" + strings.Join(streams, "/")
 	backoff := time.Second
 	for {
 		select {
@@ -42,7 +87,27 @@ func (b *BinanceClient) StartStream(ctx context.Context, priceChan chan<- model.
 				select {
 				case <-ctx.Done():
 					return nil
-				case <-time.After(backoff):
+				case <-b.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+
+			// Fetch the snapshots only after subscribing so that no update is lost in between
+			books := make(map[string]*model.OrderBook, len(pairs))
+			synced := make(map[string]bool, len(pairs))
+			if err := b.loadSnapshots(ctx, books, symbols); err != nil {
+				b.logger.Error("BinanceClient: failed to load order book snapshot", "error", err)
+				if closeErr := c.Close(); closeErr != nil {
+					b.logger.Warn("BinanceClient: failed to close connection", "error", closeErr)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-b.clock.After(backoff):
 					backoff *= 2
 					if backoff > 16*time.Second {
 						backoff = 16 * time.Second
@@ -56,6 +121,7 @@ func (b *BinanceClient) StartStream(ctx context.Context, priceChan chan<- model.
 			b.logger.Info("BinanceClient: connected successfully")
 
 			// Handle incoming messages
+		messages:
 			for {
 				select {
 				case <-ctx.Done():
@@ -66,58 +132,127 @@ func (b *BinanceClient) StartStream(ctx context.Context, priceChan chan<- model.
 					return nil
 				default:
 					_, message, err := c.ReadMessage()
+					receivedAt := b.clock.Now()
 					if err != nil {
 						b.logger.Error("BinanceClient: failed to read message", "error", err)
 						if closeErr := c.Close(); closeErr != nil {
 							b.logger.Warn("BinanceClient: failed to close connection", "error", closeErr)
 						}
 						// Break out of message loop to trigger reconnection
-						break
+						break messages
 					}
 
 					// Parse the message
-					var tickerData map[string]interface{}
-					if err := json.Unmarshal(message, &tickerData); err != nil {
+					var msg binanceStreamMessage
+					if err := json.Unmarshal(message, &msg); err != nil {
 						b.logger.Warn("BinanceClient: failed to parse message", "error", err)
 						continue
 					}
+					update := msg.Data
+					pair, ok := symbols.Canonical(update.Symbol)
+					if !ok {
+						b.logger.Warn("BinanceClient: received data for unknown symbol", "symbol", update.Symbol)
+						continue
+					}
+					book := books[pair]
+
+					// Drop updates already contained in the snapshot
+					if update.FinalUpdateID <= book.Sequence {
+						continue
+					}
+
+					// The first applied update must straddle the snapshot, later ones must be contiguous
+					if (!synced[pair] && update.FirstUpdateID > book.Sequence+1) || (synced[pair] && update.FirstUpdateID != book.Sequence+1) {
+						b.logger.Warn("BinanceClient: order book sequence gap, resyncing", "pair", pair,
+							"expected", book.Sequence+1, "received", update.FirstUpdateID)
+						if closeErr := c.Close(); closeErr != nil {
+							b.logger.Warn("BinanceClient: failed to close connection", "error", closeErr)
+						}
+						break messages
+					}
+
+					bids, err := parseLevels(update.Bids)
+					if err != nil {
+						b.logger.Warn("BinanceClient: failed to parse bid levels", "error", err)
+						continue
+					}
+					asks, err := parseLevels(update.Asks)
+					if err != nil {
+						b.logger.Warn("BinanceClient: failed to parse ask levels", "error", err)
+						continue
+					}
+					if err := book.ApplyDelta(bids, asks, update.FinalUpdateID); err != nil {
+						b.logger.Warn("BinanceClient: failed to apply book update", "error", err)
+						continue
+					}
+					synced[pair] = true
+					book.Truncate(maxBookLevels)
 
-					// Extract bid and ask prices from Binance ticker format
-					if bidStr, ok := tickerData["b"].(string); ok {
-						if askStr, ok := tickerData["a"].(string); ok {
-							bid, err := strconv.ParseFloat(bidStr, 64)
-							if err != nil {
-								b.logger.Warn("BinanceClient: failed to parse bid price", "error", err)
-								continue
-							}
-							ask, err := strconv.ParseFloat(askStr, 64)
-							if err != nil {
-								b.logger.Warn("BinanceClient: failed to parse ask price", "error", err)
-								continue
-							}
-
-							// Create and send price tick
-							tick := model.PriceTick{
-								Exchange: "binance",
-								Pair:     "BTC/EUR",
-								Bid:      bid,
-								Ask:      ask,
-							}
-
-							select {
-							case priceChan <- tick:
-								b.logger.Debug("BinanceClient: sent price tick", "bid", bid, "ask", ask)
-							case <-ctx.Done():
-								b.logger.Info("BinanceClient: context cancelled while sending price tick")
-								if closeErr := c.Close(); closeErr != nil {
-									b.logger.Warn("BinanceClient: failed to close connection", "error", closeErr)
-								}
-								return nil
-							}
+					tick, ok := book.Tick(bookDepth)
+					if !ok {
+						continue
+					}
+					tick.ExchangeTime = time.UnixMilli(update.EventTime)
+					tick.ReceivedAt = receivedAt
+
+					select {
+					case priceChan <- tick:
+						b.logger.Debug("BinanceClient: sent price tick", "pair", pair, "bid", tick.Bid, "ask", tick.Ask,
+							"feedLatency", tick.ReceivedAt.Sub(tick.ExchangeTime))
+					case <-ctx.Done():
+						b.logger.Info("BinanceClient: context cancelled while sending price tick")
+						if closeErr := c.Close(); closeErr != nil {
+							b.logger.Warn("BinanceClient: failed to close connection", "error", closeErr)
 						}
+						return nil
 					}
 				}
 			}
 		}
 	}
 }
+
+// loadSnapshots seeds a fresh book for every pair with a REST depth snapshot.
+func (b *BinanceClient) loadSnapshots(ctx context.Context, books map[string]*model.OrderBook, symbols symbolMap) error {
+	for _, pair := range symbols.pairs {
+		book := model.NewOrderBook("binance", pair)
+		if err := b.loadSnapshot(ctx, book, symbols.Native(pair)); err != nil {
+			return fmt.Errorf("%s: %w", pair, err)
+		}
+		books[pair] = book
+	}
+	return nil
+}
+
+// loadSnapshot seeds the book with a REST depth snapshot for the given symbol.
+func (b *BinanceClient) loadSnapshot(ctx context.Context, book *model.OrderBook, symbol string) error {
+	url := fmt.Sprintf("https://api.binance.com/api/v3/depth?symbol=%s&limit=1000", symbol)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var snapshot binanceDepthSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return err
+	}
+	bids, err := parseLevels(snapshot.Bids)
+	if err != nil {
+		return err
+	}
+	asks, err := parseLevels(snapshot.Asks)
+	if err != nil {
+		return err
+	}
+	book.ApplySnapshot(bids, asks, snapshot.LastUpdateID)
+	book.Truncate(maxBookLevels)
+	return nil
+}
@@ -0,0 +1,296 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"referee/internal/clock"
+	"referee/internal/instrument"
+	"referee/internal/model"
+)
+
+// BitstampClient implements the ExchangeClient interface for Bitstamp.
+type BitstampClient struct {
+	logger      *slog.Logger
+	clock       clock.Clock
+	instruments *instrument.Registry
+}
+
+// NewBitstampClient creates a new BitstampClient.
+func NewBitstampClient(logger *slog.Logger, clk clock.Clock, instruments *instrument.Registry) *BitstampClient {
+	return &BitstampClient{logger: logger, clock: clk, instruments: instruments}
+}
+
+func (b *BitstampClient) GetName() string {
+	return "bitstamp"
+}
+
+// bitstampMessage wraps every event received from the v2 WebSocket API.
+type bitstampMessage struct {
+	Event   string          `json:"event"`
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// bitstampBook is a message of the order_book channel, which always carries the top
+// 100 levels of each side.
+type bitstampBook struct {
+	Microtimestamp string        `json:"microtimestamp"`
+	Bids           []interface{} `json:"bids"`
+	Asks           []interface{} `json:"asks"`
+}
+
+// bitstampTrade is a message of the live_trades channel.
+type bitstampTrade struct {
+	Price  float64 `json:"price"`
+	Amount float64 `json:"amount"`
+	Type   int     `json:"type"` // 0 is a buy, 1 a sell
+}
+
+// StartStream connects to the Bitstamp WebSocket API and streams order book ticks for all pairs.
+// Every order_book message is a full snapshot of the top of the book, so no local state
+// survives a reconnect. Trades from the live_trades channel are only logged.
+// Bitstamp asks clients to reconnect before maintenance with a bts:request_reconnect event.
+func (b *BitstampClient) StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pairs []string) error {
+	const wsURL = "wss://ws.bitstamp.net"
+	symbols, err := newSymbolMap(b.instruments, b.GetName(), pairs)
+	if err != nil {
+		return err
+	}
+	// Channel names carry the lower-case symbol, e.g. order_book_btceur
+	channelPairs := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		channelPairs[strings.ToLower(symbols.Native(pair))] = pair
+	}
+	backoff := time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			b.logger.Info("BitstampClient: context cancelled, shutting down")
+			return nil
+		default:
+			b.logger.Info("BitstampClient: connecting to WebSocket", "url", wsURL, "backoff", backoff)
+			c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			if err != nil {
+				b.logger.Error("BitstampClient: WebSocket connection failed", "error", err)
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-b.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+
+			// Reset backoff on successful connection
+			backoff = time.Second
+
+			// Send one subscription per channel and pair
+			if err := b.subscribe(c, channelPairs); err != nil {
+				b.logger.Error("BitstampClient: failed to send subscription", "error", err)
+				if closeErr := c.Close(); closeErr != nil {
+					b.logger.Warn("BitstampClient: failed to close connection", "error", closeErr)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-b.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+			b.logger.Info("BitstampClient: subscription sent successfully")
+
+			books := make(map[string]*model.OrderBook, len(pairs))
+			for _, pair := range pairs {
+				books[pair] = model.NewOrderBook("bitstamp", pair)
+			}
+
+			// Handle incoming messages
+		messages:
+			for {
+				select {
+				case <-ctx.Done():
+					b.logger.Info("BitstampClient: context cancelled, closing connection")
+					if closeErr := c.Close(); closeErr != nil {
+						b.logger.Warn("BitstampClient: failed to close connection", "error", closeErr)
+					}
+					return nil
+				default:
+					_, message, err := c.ReadMessage()
+					receivedAt := b.clock.Now()
+					if err != nil {
+						b.logger.Error("BitstampClient: failed to read message", "error", err)
+						if closeErr := c.Close(); closeErr != nil {
+							b.logger.Warn("BitstampClient: failed to close connection", "error", closeErr)
+						}
+						// Break out of message loop to trigger reconnection
+						break messages
+					}
+
+					// Parse the message
+					var msg bitstampMessage
+					if err := json.Unmarshal(message, &msg); err != nil {
+						b.logger.Warn("BitstampClient: failed to parse message", "error", err)
+						continue
+					}
+
+					switch msg.Event {
+					case "bts:subscription_succeeded":
+						b.logger.Info("BitstampClient: subscription confirmed", "channel", msg.Channel)
+						continue
+					case "bts:error":
+						b.logger.Warn("BitstampClient: received error", "channel", msg.Channel, "data", string(msg.Data))
+						continue
+					case "bts:request_reconnect":
+						b.logger.Info("BitstampClient: reconnect requested by exchange")
+						if closeErr := c.Close(); closeErr != nil {
+							b.logger.Warn("BitstampClient: failed to close connection", "error", closeErr)
+						}
+						break messages
+					case "trade":
+						b.logTrade(msg, books, channelPairs)
+						continue
+					case "data":
+						// Order book snapshots are handled below
+					default:
+						continue
+					}
+
+					native, ok := strings.CutPrefix(msg.Channel, "order_book_")
+					if !ok {
+						continue
+					}
+					pair, ok := channelPairs[native]
+					if !ok {
+						b.logger.Warn("BitstampClient: received data for unknown channel", "channel", msg.Channel)
+						continue
+					}
+					book := books[pair]
+
+					exchangeTime, err := applyBitstampBook(book, msg.Data)
+					if errors.Is(err, model.ErrStaleUpdate) {
+						b.logger.Debug("BitstampClient: dropped out of order book snapshot", "pair", pair)
+						continue
+					}
+					if err != nil {
+						b.logger.Warn("BitstampClient: failed to apply book snapshot", "error", err)
+						continue
+					}
+
+					tick, ok := book.Tick(bookDepth)
+					if !ok {
+						continue
+					}
+					tick.ExchangeTime = exchangeTime
+					tick.ReceivedAt = receivedAt
+
+					select {
+					case priceChan <- tick:
+						b.logger.Debug("BitstampClient: sent price tick", "pair", pair, "bid", tick.Bid, "ask", tick.Ask,
+							"feedLatency", tick.ReceivedAt.Sub(tick.ExchangeTime))
+					case <-ctx.Done():
+						b.logger.Info("BitstampClient: context cancelled while sending price tick")
+						if closeErr := c.Close(); closeErr != nil {
+							b.logger.Warn("BitstampClient: failed to close connection", "error", closeErr)
+						}
+						return nil
+					}
+				}
+			}
+		}
+	}
+}
+
+// subscribe sends the order book and live trades subscriptions for every pair.
+func (b *BitstampClient) subscribe(c *websocket.Conn, channelPairs map[string]string) error {
+	for native := range channelPairs {
+		for _, channel := range []string{"order_book_" + native, "live_trades_" + native} {
+			subscription := map[string]interface{}{
+				"event": "bts:subscribe",
+				"data":  map[string]interface{}{"channel": channel},
+			}
+			if err := c.WriteJSON(subscription); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyBitstampBook replaces the book with an order_book snapshot and returns its
+// timestamp. The microsecond timestamp serves as the sequence number, so a snapshot
+// that is not newer than the book returns model.ErrStaleUpdate and is ignored.
+func applyBitstampBook(book *model.OrderBook, data json.RawMessage) (time.Time, error) {
+	var snapshot bitstampBook
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return time.Time{}, err
+	}
+	micros, err := strconv.ParseInt(snapshot.Microtimestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	exchangeTime := time.UnixMicro(micros)
+	if micros <= book.Sequence {
+		return exchangeTime, model.ErrStaleUpdate
+	}
+	bids, err := parseLevels(snapshot.Bids)
+	if err != nil {
+		return time.Time{}, err
+	}
+	asks, err := parseLevels(snapshot.Asks)
+	if err != nil {
+		return time.Time{}, err
+	}
+	book.ApplySnapshot(bids, asks, micros)
+	return exchangeTime, nil
+}
+
+// logTrade logs a live trade together with the local top of book, for comparing the
+// prices Bitstamp fills at with the quotes the engine sees.
+func (b *BitstampClient) logTrade(msg bitstampMessage, books map[string]*model.OrderBook, channelPairs map[string]string) {
+	pair, trade, err := parseBitstampTrade(msg, channelPairs)
+	if err != nil {
+		b.logger.Warn("BitstampClient: failed to parse trade", "error", err)
+		return
+	}
+	if pair == "" {
+		return
+	}
+	bid, ask := 0.0, 0.0
+	if tick, ok := books[pair].Tick(1); ok {
+		bid, ask = tick.Bid, tick.Ask
+	}
+	b.logger.Debug("BitstampClient: received trade", "pair", pair, "price", trade.Price, "amount", trade.Amount,
+		"buy", trade.Type == 0, "bid", bid, "ask", ask)
+}
+
+// parseBitstampTrade returns the pair and trade of a live_trades message. The pair is
+// empty for messages of other channels or of pairs not being streamed.
+func parseBitstampTrade(msg bitstampMessage, channelPairs map[string]string) (string, bitstampTrade, error) {
+	native, ok := strings.CutPrefix(msg.Channel, "live_trades_")
+	if !ok {
+		return "", bitstampTrade{}, nil
+	}
+	pair, ok := channelPairs[native]
+	if !ok {
+		return "", bitstampTrade{}, nil
+	}
+	var trade bitstampTrade
+	if err := json.Unmarshal(msg.Data, &trade); err != nil {
+		return "", bitstampTrade{}, err
+	}
+	return pair, trade, nil
+}
@@ -0,0 +1,136 @@
+package exchange
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"referee/internal/model"
+)
+
+func TestApplyBitstampBook(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bids := []model.PriceLevel{{Price: 60000, Size: 1}, {Price: 59990, Size: 2}}
+	asks := []model.PriceLevel{{Price: 60010, Size: 0.5}}
+
+	tests := []struct {
+		name      string
+		data      string
+		wantStale bool
+		wantErr   bool
+		wantTime  time.Time
+		wantSeq   int64
+		wantBids  []model.PriceLevel
+		wantAsks  []model.PriceLevel
+	}{
+		{
+			name:     "newer snapshot replaces every level",
+			data:     `{"microtimestamp":"1704067200000500","bids":[["60005","3"]],"asks":[["60008","1"],["60020","2"]]}`,
+			wantTime: start.Add(500 * time.Microsecond),
+			wantSeq:  start.UnixMicro() + 500,
+			wantBids: []model.PriceLevel{{Price: 60005, Size: 3}},
+			wantAsks: []model.PriceLevel{{Price: 60008, Size: 1}, {Price: 60020, Size: 2}},
+		},
+		{
+			name:     "empty side clears the levels",
+			data:     `{"microtimestamp":"1704067200000001","bids":[],"asks":[["60010","0.5"]]}`,
+			wantTime: start.Add(time.Microsecond),
+			wantSeq:  start.UnixMicro() + 1,
+			wantBids: []model.PriceLevel{},
+			wantAsks: asks,
+		},
+		{
+			name:      "repeated timestamp",
+			data:      `{"microtimestamp":"1704067200000000","bids":[["1","1"]],"asks":[["2","1"]]}`,
+			wantStale: true,
+			wantTime:  start,
+			wantSeq:   start.UnixMicro(),
+			wantBids:  bids,
+			wantAsks:  asks,
+		},
+		{
+			name:      "older than the book",
+			data:      `{"microtimestamp":"1704067199999000","bids":[["1","1"]],"asks":[["2","1"]]}`,
+			wantStale: true,
+			wantTime:  start.Add(-time.Millisecond),
+			wantSeq:   start.UnixMicro(),
+			wantBids:  bids,
+			wantAsks:  asks,
+		},
+		{
+			name:     "malformed timestamp",
+			data:     `{"microtimestamp":"soon","bids":[],"asks":[]}`,
+			wantErr:  true,
+			wantSeq:  start.UnixMicro(),
+			wantBids: bids,
+			wantAsks: asks,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := model.NewOrderBook("bitstamp", "BTC/EUR")
+			book.ApplySnapshot(bids, asks, start.UnixMicro())
+
+			exchangeTime, err := applyBitstampBook(book, json.RawMessage(tt.data))
+			switch {
+			case tt.wantStale:
+				assert.ErrorIs(t, err, model.ErrStaleUpdate)
+				assert.True(t, exchangeTime.Equal(tt.wantTime))
+			case tt.wantErr:
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, model.ErrStaleUpdate)
+			default:
+				assert.NoError(t, err)
+				assert.True(t, exchangeTime.Equal(tt.wantTime))
+			}
+			assert.Equal(t, tt.wantSeq, book.Sequence)
+			assert.Equal(t, tt.wantBids, book.Bids(0))
+			assert.Equal(t, tt.wantAsks, book.Asks(0))
+		})
+	}
+}
+
+func TestParseBitstampTrade(t *testing.T) {
+	channelPairs := map[string]string{"btceur": "BTC/EUR"}
+
+	tests := []struct {
+		name      string
+		msg       bitstampMessage
+		wantPair  string
+		wantTrade bitstampTrade
+		wantErr   bool
+	}{
+		{
+			name:      "live trade",
+			msg:       bitstampMessage{Event: "trade", Channel: "live_trades_btceur", Data: json.RawMessage(`{"price":60005.5,"amount":0.25,"type":1}`)},
+			wantPair:  "BTC/EUR",
+			wantTrade: bitstampTrade{Price: 60005.5, Amount: 0.25, Type: 1},
+		},
+		{
+			name: "unknown pair",
+			msg:  bitstampMessage{Event: "trade", Channel: "live_trades_etheur", Data: json.RawMessage(`{"price":3000,"amount":1,"type":0}`)},
+		},
+		{
+			name: "other channel",
+			msg:  bitstampMessage{Event: "data", Channel: "order_book_btceur", Data: json.RawMessage(`{"bids":[],"asks":[]}`)},
+		},
+		{
+			name:    "malformed trade",
+			msg:     bitstampMessage{Event: "trade", Channel: "live_trades_btceur", Data: json.RawMessage(`{"price":"cheap"}`)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, trade, err := parseBitstampTrade(tt.msg, channelPairs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantPair, pair)
+			assert.Equal(t, tt.wantTrade, trade)
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"referee/internal/clock"
+	"referee/internal/instrument"
+	"referee/internal/model"
+)
+
+// BitvavoClient implements the ExchangeClient interface for Bitvavo.
+type BitvavoClient struct {
+	logger      *slog.Logger
+	clock       clock.Clock
+	instruments *instrument.Registry
+}
+
+// NewBitvavoClient creates a new BitvavoClient.
+func NewBitvavoClient(logger *slog.Logger, clk clock.Clock, instruments *instrument.Registry) *BitvavoClient {
+	return &BitvavoClient{logger: logger, clock: clk, instruments: instruments}
+}
+
+func (b *BitvavoClient) GetName() string {
+	return "bitvavo"
+}
+
+// bitvavoMessage is any message received from the v2 WebSocket API. Subscription events
+// carry Event, responses to requests such as getBook carry Action and Response.
+type bitvavoMessage struct {
+	Event    string          `json:"event"`
+	Action   string          `json:"action"`
+	Error    string          `json:"error"`
+	Response json.RawMessage `json:"response"`
+}
+
+// bitvavoBook is a book update event, or the response to a getBook request. Updates set
+// the size of each level; a zero size removes it.
+type bitvavoBook struct {
+	Market    string        `json:"market"`
+	Nonce     int64         `json:"nonce"`
+	Bids      []interface{} `json:"bids"`
+	Asks      []interface{} `json:"asks"`
+	Timestamp int64         `json:"timestamp"`
+}
+
+// bitvavoTicker is a ticker event. Only the fields that changed are sent.
+type bitvavoTicker struct {
+	Market      string `json:"market"`
+	BestBid     string `json:"bestBid"`
+	BestBidSize string `json:"bestBidSize"`
+	BestAsk     string `json:"bestAsk"`
+	BestAskSize string `json:"bestAskSize"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// StartStream connects to the Bitvavo WebSocket API and streams order book ticks for all pairs.
+// Each local book is seeded from a getBook snapshot and kept in sync with the book
+// subscription, whose nonces must be contiguous; a gap triggers a fresh snapshot. Until
+// a pair's book is synced, its top of book comes from the ticker subscription.
+func (b *BitvavoClient) StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pairs []string) error {
+	const wsURL = "wss://ws.bitvavo.com/v2/"
+	symbols, err := newSymbolMap(b.instruments, b.GetName(), pairs)
+	if err != nil {
+		return err
+	}
+	backoff := time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			b.logger.Info("BitvavoClient: context cancelled, shutting down")
+			return nil
+		default:
+			b.logger.Info("BitvavoClient: connecting to WebSocket", "url", wsURL, "backoff", backoff)
+			c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			if err != nil {
+				b.logger.Error("BitvavoClient: WebSocket connection failed", "error", err)
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-b.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+
+			// Reset backoff on successful connection
+			backoff = time.Second
+
+			// Subscribe before requesting the snapshots so that no update is lost in between
+			subscription := map[string]interface{}{
+				"action": "subscribe",
+				"channels": []map[string]interface{}{
+					{"name": "book", "markets": symbols.Natives()},
+					{"name": "ticker", "markets": symbols.Natives()},
+				},
+			}
+			err = c.WriteJSON(subscription)
+			for _, native := range symbols.Natives() {
+				if err != nil {
+					break
+				}
+				err = b.requestBook(c, native)
+			}
+			if err != nil {
+				b.logger.Error("BitvavoClient: failed to send subscription", "error", err)
+				if closeErr := c.Close(); closeErr != nil {
+					b.logger.Warn("BitvavoClient: failed to close connection", "error", closeErr)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-b.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+			b.logger.Info("BitvavoClient: subscription sent successfully")
+
+			books := make(map[string]*model.OrderBook, len(pairs))
+			synced := make(map[string]bool, len(pairs))
+			pending := make(map[string][]bitvavoBook, len(pairs)) // Updates received before the snapshot
+			tickers := make(map[string]bitvavoTicker, len(pairs))
+			for _, pair := range pairs {
+				books[pair] = model.NewOrderBook("bitvavo", pair)
+			}
+
+			// Handle incoming messages
+		messages:
+			for {
+				select {
+				case <-ctx.Done():
+					b.logger.Info("BitvavoClient: context cancelled, closing connection")
+					if closeErr := c.Close(); closeErr != nil {
+						b.logger.Warn("BitvavoClient: failed to close connection", "error", closeErr)
+					}
+					return nil
+				default:
+					_, message, err := c.ReadMessage()
+					receivedAt := b.clock.Now()
+					if err != nil {
+						b.logger.Error("BitvavoClient: failed to read message", "error", err)
+						if closeErr := c.Close(); closeErr != nil {
+							b.logger.Warn("BitvavoClient: failed to close connection", "error", closeErr)
+						}
+						// Break out of message loop to trigger reconnection
+						break messages
+					}
+
+					// Parse the message
+					var msg bitvavoMessage
+					if err := json.Unmarshal(message, &msg); err != nil {
+						b.logger.Warn("BitvavoClient: failed to parse message", "error", err)
+						continue
+					}
+					if msg.Error != "" {
+						b.logger.Warn("BitvavoClient: received error", "action", msg.Action, "error", msg.Error)
+						continue
+					}
+
+					var pair string
+					var exchangeTime time.Time
+					switch {
+					case msg.Event == "subscribed":
+						b.logger.Info("BitvavoClient: subscription confirmed")
+						continue
+
+					case msg.Action == "getBook":
+						var snapshot bitvavoBook
+						if err := json.Unmarshal(msg.Response, &snapshot); err != nil {
+							b.logger.Warn("BitvavoClient: failed to parse book snapshot", "error", err)
+							continue
+						}
+						var ok bool
+						if pair, ok = symbols.Canonical(snapshot.Market); !ok {
+							continue
+						}
+						if err := applyBitvavoSnapshot(books[pair], snapshot, pending[pair]); err != nil {
+							b.logger.Warn("BitvavoClient: order book nonce gap, resyncing", "pair", pair, "error", err)
+							pending[pair] = nil
+							if err := b.requestBook(c, snapshot.Market); err != nil {
+								b.logger.Error("BitvavoClient: failed to request book snapshot", "error", err)
+								if closeErr := c.Close(); closeErr != nil {
+									b.logger.Warn("BitvavoClient: failed to close connection", "error", closeErr)
+								}
+								break messages
+							}
+							continue
+						}
+						pending[pair] = nil
+						synced[pair] = true
+						exchangeTime = bitvavoTime(snapshot.Timestamp)
+
+					case msg.Event == "book":
+						var update bitvavoBook
+						if err := json.Unmarshal(message, &update); err != nil {
+							b.logger.Warn("BitvavoClient: failed to parse book update", "error", err)
+							continue
+						}
+						var ok bool
+						if pair, ok = symbols.Canonical(update.Market); !ok {
+							b.logger.Warn("BitvavoClient: received data for unknown market", "market", update.Market)
+							continue
+						}
+						if !synced[pair] {
+							pending[pair] = append(pending[pair], update)
+							continue
+						}
+						err := applyBitvavoUpdate(books[pair], update)
+						if errors.Is(err, model.ErrStaleUpdate) {
+							b.logger.Debug("BitvavoClient: dropped stale book update", "pair", pair, "nonce", update.Nonce)
+							continue
+						}
+						if err != nil {
+							b.logger.Warn("BitvavoClient: order book nonce gap, resyncing", "pair", pair, "error", err)
+							synced[pair] = false
+							if err := b.requestBook(c, update.Market); err != nil {
+								b.logger.Error("BitvavoClient: failed to request book snapshot", "error", err)
+								if closeErr := c.Close(); closeErr != nil {
+									b.logger.Warn("BitvavoClient: failed to close connection", "error", closeErr)
+								}
+								break messages
+							}
+							continue
+						}
+						exchangeTime = bitvavoTime(update.Timestamp)
+
+					case msg.Event == "ticker":
+						var ticker bitvavoTicker
+						if err := json.Unmarshal(message, &ticker); err != nil {
+							b.logger.Warn("BitvavoClient: failed to parse ticker", "error", err)
+							continue
+						}
+						var ok bool
+						if pair, ok = symbols.Canonical(ticker.Market); !ok {
+							continue
+						}
+						tickers[pair] = mergeBitvavoTicker(tickers[pair], ticker)
+						if synced[pair] {
+							// The book is the more complete source once it is synced
+							continue
+						}
+						exchangeTime = bitvavoTime(ticker.Timestamp)
+
+					default:
+						continue
+					}
+
+					var tick model.PriceTick
+					var ok bool
+					if synced[pair] {
+						tick, ok = books[pair].Tick(bookDepth)
+					} else {
+						tick, ok = bitvavoTickerTick(pair, tickers[pair])
+					}
+					if !ok {
+						continue
+					}
+					tick.ExchangeTime = exchangeTime
+					tick.ReceivedAt = receivedAt
+
+					select {
+					case priceChan <- tick:
+						b.logger.Debug("BitvavoClient: sent price tick", "pair", pair, "bid", tick.Bid, "ask", tick.Ask,
+							"feedLatency", tick.ReceivedAt.Sub(tick.ExchangeTime))
+					case <-ctx.Done():
+						b.logger.Info("BitvavoClient: context cancelled while sending price tick")
+						if closeErr := c.Close(); closeErr != nil {
+							b.logger.Warn("BitvavoClient: failed to close connection", "error", closeErr)
+						}
+						return nil
+					}
+				}
+			}
+		}
+	}
+}
+
+// requestBook asks for a snapshot of a market's order book.
+func (b *BitvavoClient) requestBook(c *websocket.Conn, market string) error {
+	return c.WriteJSON(map[string]interface{}{
+		"action": "getBook",
+		"market": market,
+		"depth":  maxBookLevels,
+	})
+}
+
+// applyBitvavoSnapshot seeds the book with a getBook response and replays the updates
+// that arrived while it was requested. Updates already contained in the snapshot are
+// dropped; the rest must continue its nonce without a gap.
+func applyBitvavoSnapshot(book *model.OrderBook, snapshot bitvavoBook, pending []bitvavoBook) error {
+	bids, err := parseLevels(snapshot.Bids)
+	if err != nil {
+		return err
+	}
+	asks, err := parseLevels(snapshot.Asks)
+	if err != nil {
+		return err
+	}
+	book.ApplySnapshot(bids, asks, snapshot.Nonce)
+	for _, update := range pending {
+		if update.Nonce <= snapshot.Nonce {
+			continue
+		}
+		if err := applyBitvavoUpdate(book, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyBitvavoUpdate applies a book update whose nonce must directly follow the book's.
+// An update the book already contains returns model.ErrStaleUpdate, a gap any other error.
+func applyBitvavoUpdate(book *model.OrderBook, update bitvavoBook) error {
+	if update.Nonce <= book.Sequence {
+		return model.ErrStaleUpdate
+	}
+	if update.Nonce != book.Sequence+1 {
+		return fmt.Errorf("expected nonce %d, received %d", book.Sequence+1, update.Nonce)
+	}
+	bids, err := parseLevels(update.Bids)
+	if err != nil {
+		return err
+	}
+	asks, err := parseLevels(update.Asks)
+	if err != nil {
+		return err
+	}
+	if err := book.ApplyDelta(bids, asks, update.Nonce); err != nil {
+		return err
+	}
+	book.Truncate(maxBookLevels)
+	return nil
+}
+
+// mergeBitvavoTicker applies the fields of a ticker event to the last known ticker.
+func mergeBitvavoTicker(current, update bitvavoTicker) bitvavoTicker {
+	current.Market, current.Timestamp = update.Market, update.Timestamp
+	if update.BestBid != "" {
+		current.BestBid, current.BestBidSize = update.BestBid, update.BestBidSize
+	}
+	if update.BestAsk != "" {
+		current.BestAsk, current.BestAskSize = update.BestAsk, update.BestAskSize
+	}
+	return current
+}
+
+// bitvavoTime converts a Bitvavo timestamp, which is in milliseconds on some channels and
+// in nanoseconds on others, to a time. A missing timestamp is the zero time.
+func bitvavoTime(ts int64) time.Time {
+	switch {
+	case ts <= 0:
+		return time.Time{}
+	case ts > 1e15:
+		return time.Unix(0, ts)
+	default:
+		return time.UnixMilli(ts)
+	}
+}
+
+// bitvavoTickerTick builds a tick carrying only the top of book of a ticker. The
+// second return value is false until both sides are known.
+func bitvavoTickerTick(pair string, ticker bitvavoTicker) (model.PriceTick, bool) {
+	bid, bidErr := parseLevel(ticker.BestBid, ticker.BestBidSize)
+	ask, askErr := parseLevel(ticker.BestAsk, ticker.BestAskSize)
+	if bidErr != nil || askErr != nil {
+		return model.PriceTick{}, false
+	}
+	return model.PriceTick{
+		Exchange: "bitvavo",
+		Pair:     pair,
+		Bid:      bid.Price,
+		Ask:      ask.Price,
+		Bids:     []model.PriceLevel{bid},
+		Asks:     []model.PriceLevel{ask},
+	}, true
+}
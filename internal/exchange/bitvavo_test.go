@@ -0,0 +1,119 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"referee/internal/model"
+)
+
+// bitvavoLevels builds the raw [price, size] arrays of a Bitvavo book message.
+func bitvavoLevels(levels ...[2]string) []interface{} {
+	raw := make([]interface{}, 0, len(levels))
+	for _, level := range levels {
+		raw = append(raw, []interface{}{level[0], level[1]})
+	}
+	return raw
+}
+
+func TestApplyBitvavoSnapshot(t *testing.T) {
+	snapshot := bitvavoBook{
+		Market: "BTC-EUR",
+		Nonce:  10,
+		Bids:   bitvavoLevels([2]string{"60000", "1"}, [2]string{"59990", "2"}),
+		Asks:   bitvavoLevels([2]string{"60010", "1"}),
+	}
+
+	tests := []struct {
+		name     string
+		pending  []bitvavoBook
+		wantErr  bool
+		wantSeq  int64
+		wantBids []model.PriceLevel
+	}{
+		{
+			name:     "no pending updates",
+			wantSeq:  10,
+			wantBids: []model.PriceLevel{{Price: 60000, Size: 1}, {Price: 59990, Size: 2}},
+		},
+		{
+			name: "stale pending updates are dropped and the rest replayed in order",
+			pending: []bitvavoBook{
+				{Nonce: 9, Bids: bitvavoLevels([2]string{"1", "1"})},
+				{Nonce: 10, Bids: bitvavoLevels([2]string{"2", "1"})},
+				{Nonce: 11, Bids: bitvavoLevels([2]string{"60000", "0"})},
+				{Nonce: 12, Bids: bitvavoLevels([2]string{"60005", "3"})},
+			},
+			wantSeq:  12,
+			wantBids: []model.PriceLevel{{Price: 60005, Size: 3}, {Price: 59990, Size: 2}},
+		},
+		{
+			name: "gap after the snapshot",
+			pending: []bitvavoBook{
+				{Nonce: 12, Bids: bitvavoLevels([2]string{"60005", "3"})},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := model.NewOrderBook("bitvavo", "BTC/EUR")
+			err := applyBitvavoSnapshot(book, snapshot, tt.pending)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSeq, book.Sequence)
+			assert.Equal(t, tt.wantBids, book.Bids(0))
+		})
+	}
+}
+
+func TestApplyBitvavoUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		nonce     int64
+		wantStale bool
+		wantErr   bool
+		wantSeq   int64
+		wantBids  []model.PriceLevel
+	}{
+		{
+			name:     "in order",
+			nonce:    11,
+			wantSeq:  11,
+			wantBids: []model.PriceLevel{{Price: 60005, Size: 3}, {Price: 60000, Size: 1}},
+		},
+		{name: "gap", nonce: 13, wantErr: true, wantSeq: 10},
+		{name: "stale", nonce: 10, wantStale: true, wantSeq: 10},
+		{name: "older than the book", nonce: 5, wantStale: true, wantSeq: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := model.NewOrderBook("bitvavo", "BTC/EUR")
+			book.ApplySnapshot([]model.PriceLevel{{Price: 60000, Size: 1}}, []model.PriceLevel{{Price: 60010, Size: 1}}, 10)
+
+			err := applyBitvavoUpdate(book, bitvavoBook{Nonce: tt.nonce, Bids: bitvavoLevels([2]string{"60005", "3"})})
+			switch {
+			case tt.wantStale:
+				assert.ErrorIs(t, err, model.ErrStaleUpdate)
+			case tt.wantErr:
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, model.ErrStaleUpdate)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantBids, book.Bids(0))
+			}
+			assert.Equal(t, tt.wantSeq, book.Sequence)
+		})
+	}
+}
+
+func TestBitvavoTime(t *testing.T) {
+	want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.True(t, bitvavoTime(want.UnixMilli()).Equal(want))
+	assert.True(t, bitvavoTime(want.UnixNano()).Equal(want))
+	assert.True(t, bitvavoTime(0).IsZero())
+}
@@ -0,0 +1,253 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"referee/internal/clock"
+	"referee/internal/instrument"
+	"referee/internal/model"
+)
+
+// bybitPingInterval is how often a ping is sent, as recommended by Bybit to keep the
+// connection open.
+const bybitPingInterval = 20 * time.Second
+
+// bybitBookDepth is the depth of the subscribed order books.
+const bybitBookDepth = 50
+
+// BybitClient implements the ExchangeClient interface for Bybit spot markets.
+type BybitClient struct {
+	logger      *slog.Logger
+	clock       clock.Clock
+	instruments *instrument.Registry
+}
+
+// NewBybitClient creates a new BybitClient.
+func NewBybitClient(logger *slog.Logger, clk clock.Clock, instruments *instrument.Registry) *BybitClient {
+	return &BybitClient{logger: logger, clock: clk, instruments: instruments}
+}
+
+func (b *BybitClient) GetName() string {
+	return "bybit"
+}
+
+// bybitMessage is a push or operation response of the v5 public WebSocket API.
+type bybitMessage struct {
+	Op      string          `json:"op"`
+	Success *bool           `json:"success"`
+	RetMsg  string          `json:"ret_msg"`
+	Topic   string          `json:"topic"`
+	Type    string          `json:"type"` // "snapshot" or "delta"
+	Ts      int64           `json:"ts"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// bybitBook is the payload of an orderbook push. Deltas set the size of each level; a
+// zero size removes it.
+type bybitBook struct {
+	Symbol   string        `json:"s"`
+	Bids     []interface{} `json:"b"`
+	Asks     []interface{} `json:"a"`
+	UpdateID int64         `json:"u"`
+}
+
+// StartStream connects to the Bybit WebSocket API and streams order book ticks for all pairs.
+// Each local book is rebuilt from the snapshot Bybit sends after every subscription, or
+// whenever its service restarts, and kept up to date with the deltas that follow.
+// A {"op":"ping"} keeps the connection open.
+func (b *BybitClient) StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pairs []string) error {
+	const wsURL = "wss://stream.bybit.com/v5/public/spot"
+	symbols, err := newSymbolMap(b.instruments, b.GetName(), pairs)
+	if err != nil {
+		return err
+	}
+	ping, err := json.Marshal(map[string]string{"op": "ping"})
+	if err != nil {
+		return err
+	}
+	backoff := time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			b.logger.Info("BybitClient: context cancelled, shutting down")
+			return nil
+		default:
+			b.logger.Info("BybitClient: connecting to WebSocket", "url", wsURL, "backoff", backoff)
+			c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			if err != nil {
+				b.logger.Error("BybitClient: WebSocket connection failed", "error", err)
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-b.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+
+			// Reset backoff on successful connection
+			backoff = time.Second
+
+			// Send subscription message for the order books of every pair
+			topics := make([]string, 0, len(pairs))
+			for _, native := range symbols.Natives() {
+				topics = append(topics, fmt.Sprintf("orderbook.%d.%s", bybitBookDepth, native))
+			}
+			subscription := map[string]interface{}{
+				"op":   "subscribe",
+				"args": topics,
+			}
+			if err := c.WriteJSON(subscription); err != nil {
+				b.logger.Error("BybitClient: failed to send subscription", "error", err)
+				if closeErr := c.Close(); closeErr != nil {
+					b.logger.Warn("BybitClient: failed to close connection", "error", closeErr)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-b.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+			b.logger.Info("BybitClient: subscription sent successfully")
+			go keepAlive(ctx, b.logger, b.clock, c, bybitPingInterval, websocket.TextMessage, ping)
+
+			books := make(map[string]*model.OrderBook, len(pairs))
+			synced := make(map[string]bool, len(pairs))
+			for _, pair := range pairs {
+				books[pair] = model.NewOrderBook("bybit", pair)
+			}
+
+			// Handle incoming messages
+		messages:
+			for {
+				select {
+				case <-ctx.Done():
+					b.logger.Info("BybitClient: context cancelled, closing connection")
+					if closeErr := c.Close(); closeErr != nil {
+						b.logger.Warn("BybitClient: failed to close connection", "error", closeErr)
+					}
+					return nil
+				default:
+					_, message, err := c.ReadMessage()
+					receivedAt := b.clock.Now()
+					if err != nil {
+						b.logger.Error("BybitClient: failed to read message", "error", err)
+						if closeErr := c.Close(); closeErr != nil {
+							b.logger.Warn("BybitClient: failed to close connection", "error", closeErr)
+						}
+						// Break out of message loop to trigger reconnection
+						break messages
+					}
+
+					// Parse the message
+					var msg bybitMessage
+					if err := json.Unmarshal(message, &msg); err != nil {
+						b.logger.Warn("BybitClient: failed to parse message", "error", err)
+						continue
+					}
+
+					// Operation responses: subscription confirmations and pongs
+					if msg.Op != "" {
+						switch {
+						case msg.Success != nil && !*msg.Success:
+							b.logger.Warn("BybitClient: operation failed", "op", msg.Op, "error", msg.RetMsg)
+						case msg.Op == "subscribe":
+							b.logger.Info("BybitClient: subscription confirmed")
+						}
+						continue
+					}
+
+					native, ok := strings.CutPrefix(msg.Topic, fmt.Sprintf("orderbook.%d.", bybitBookDepth))
+					if !ok {
+						continue
+					}
+					pair, ok := symbols.Canonical(native)
+					if !ok {
+						b.logger.Warn("BybitClient: received data for unknown symbol", "symbol", native)
+						continue
+					}
+					book := books[pair]
+
+					var update bybitBook
+					if err := json.Unmarshal(msg.Data, &update); err != nil {
+						b.logger.Warn("BybitClient: failed to parse book data", "error", err)
+						continue
+					}
+					applied, err := applyBybitBook(book, msg.Type, update, synced[pair])
+					if err != nil {
+						b.logger.Warn("BybitClient: failed to apply book update", "pair", pair, "error", err)
+						continue
+					}
+					if !applied {
+						continue
+					}
+					synced[pair] = true
+
+					tick, ok := book.Tick(bookDepth)
+					if !ok {
+						continue
+					}
+					tick.ExchangeTime = time.UnixMilli(msg.Ts)
+					tick.ReceivedAt = receivedAt
+
+					select {
+					case priceChan <- tick:
+						b.logger.Debug("BybitClient: sent price tick", "pair", pair, "bid", tick.Bid, "ask", tick.Ask,
+							"feedLatency", tick.ReceivedAt.Sub(tick.ExchangeTime))
+					case <-ctx.Done():
+						b.logger.Info("BybitClient: context cancelled while sending price tick")
+						if closeErr := c.Close(); closeErr != nil {
+							b.logger.Warn("BybitClient: failed to close connection", "error", closeErr)
+						}
+						return nil
+					}
+				}
+			}
+		}
+	}
+}
+
+// applyBybitBook applies a "snapshot" or "delta" push to the book and reports whether
+// it changed. Deltas are ignored until the book is synced by a snapshot. A snapshot
+// replaces the book whatever its update ID, since Bybit restarts the IDs at 1 when its
+// service restarts.
+func applyBybitBook(book *model.OrderBook, kind string, update bybitBook, synced bool) (bool, error) {
+	bids, err := parseLevels(update.Bids)
+	if err != nil {
+		return false, err
+	}
+	asks, err := parseLevels(update.Asks)
+	if err != nil {
+		return false, err
+	}
+	switch kind {
+	case "snapshot":
+		book.ApplySnapshot(bids, asks, update.UpdateID)
+	case "delta":
+		if !synced {
+			return false, nil
+		}
+		if err := book.ApplyDelta(bids, asks, update.UpdateID); err != nil {
+			return false, err
+		}
+	default:
+		return false, nil
+	}
+	// Levels pushed out of the subscribed depth are not always removed by a delta
+	book.Truncate(bybitBookDepth)
+	return true, nil
+}
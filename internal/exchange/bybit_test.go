@@ -0,0 +1,65 @@
+package exchange
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"referee/internal/model"
+)
+
+func TestApplyBybitBook(t *testing.T) {
+	book := model.NewOrderBook("bybit", "BTC/EUR")
+	level := func(price, size string) []interface{} {
+		return []interface{}{[]interface{}{price, size}}
+	}
+
+	t.Run("delta before the snapshot is ignored", func(t *testing.T) {
+		applied, err := applyBybitBook(book, "delta", bybitBook{Bids: level("60000", "1"), UpdateID: 5}, false)
+		assert.NoError(t, err)
+		assert.False(t, applied)
+		assert.Empty(t, book.Bids(0))
+	})
+
+	t.Run("snapshot and delta", func(t *testing.T) {
+		applied, err := applyBybitBook(book, "snapshot", bybitBook{Bids: level("60000", "1"), Asks: level("60010", "2"), UpdateID: 100}, false)
+		assert.NoError(t, err)
+		assert.True(t, applied)
+
+		applied, err = applyBybitBook(book, "delta", bybitBook{Bids: level("60000", "0"), Asks: level("60008", "1"), UpdateID: 101}, true)
+		assert.NoError(t, err)
+		assert.True(t, applied)
+		assert.Empty(t, book.Bids(0))
+		assert.Equal(t, []model.PriceLevel{{Price: 60008, Size: 1}, {Price: 60010, Size: 2}}, book.Asks(0))
+		assert.Equal(t, int64(101), book.Sequence)
+	})
+
+	t.Run("stale delta is rejected", func(t *testing.T) {
+		applied, err := applyBybitBook(book, "delta", bybitBook{Bids: level("1", "1"), UpdateID: 101}, true)
+		assert.ErrorIs(t, err, model.ErrStaleUpdate)
+		assert.False(t, applied)
+	})
+
+	t.Run("snapshot after a service restart resets the update ID", func(t *testing.T) {
+		applied, err := applyBybitBook(book, "snapshot", bybitBook{Bids: level("59000", "1"), Asks: level("59010", "1"), UpdateID: 1}, true)
+		assert.NoError(t, err)
+		assert.True(t, applied)
+		assert.Equal(t, int64(1), book.Sequence)
+		assert.Equal(t, []model.PriceLevel{{Price: 59000, Size: 1}}, book.Bids(0))
+
+		applied, err = applyBybitBook(book, "delta", bybitBook{Bids: level("59005", "2"), UpdateID: 2}, true)
+		assert.NoError(t, err)
+		assert.True(t, applied)
+		assert.Equal(t, []model.PriceLevel{{Price: 59005, Size: 2}, {Price: 59000, Size: 1}}, book.Bids(0))
+	})
+
+	t.Run("book is capped at the subscribed depth", func(t *testing.T) {
+		bids := make([]interface{}, 0, bybitBookDepth+10)
+		for i := range bybitBookDepth + 10 {
+			bids = append(bids, []interface{}{fmt.Sprint(50000 + i), "1"})
+		}
+		_, err := applyBybitBook(book, "delta", bybitBook{Bids: bids, UpdateID: 3}, true)
+		assert.NoError(t, err)
+		assert.Len(t, book.Bids(0), bybitBookDepth)
+	})
+}
@@ -6,7 +6,9 @@ import (
 )
 
 // ExchangeClient defines the standard interface for all exchange clients.
+// StartStream subscribes to every canonical pair (e.g. "BTC/EUR") and emits ticks
+// whose Pair field holds the canonical pair.
 type ExchangeClient interface {
 	GetName() string
-	StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pair string) error
+	StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pairs []string) error
 }
@@ -0,0 +1,358 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"referee/internal/clock"
+	"referee/internal/instrument"
+	"referee/internal/model"
+)
+
+// CoinbaseClient implements the ExchangeClient interface for Coinbase Advanced Trade.
+type CoinbaseClient struct {
+	logger      *slog.Logger
+	clock       clock.Clock
+	instruments *instrument.Registry
+}
+
+// NewCoinbaseClient creates a new CoinbaseClient.
+func NewCoinbaseClient(logger *slog.Logger, clk clock.Clock, instruments *instrument.Registry) *CoinbaseClient {
+	return &CoinbaseClient{logger: logger, clock: clk, instruments: instruments}
+}
+
+func (cb *CoinbaseClient) GetName() string {
+	return "coinbase"
+}
+
+// coinbaseMessage is a single message of the Advanced Trade market data feed. Every
+// message on a connection carries the next sequence number, whatever its channel.
+type coinbaseMessage struct {
+	Channel     string          `json:"channel"`
+	Timestamp   string          `json:"timestamp"`
+	SequenceNum int64           `json:"sequence_num"`
+	Events      json.RawMessage `json:"events"`
+}
+
+// errSequenceGap is returned when a message does not directly follow the previous one,
+// so an update may have been missed.
+var errSequenceGap = errors.New("message sequence gap")
+
+// coinbaseBookEvent is a snapshot or update of the level2 channel.
+type coinbaseBookEvent struct {
+	Type      string               `json:"type"`
+	ProductID string               `json:"product_id"`
+	Updates   []coinbaseBookUpdate `json:"updates"`
+}
+
+// coinbaseBookUpdate sets the size of one price level; a zero size removes it.
+type coinbaseBookUpdate struct {
+	Side        string `json:"side"` // "bid" or "offer"
+	PriceLevel  string `json:"price_level"`
+	NewQuantity string `json:"new_quantity"`
+}
+
+// coinbaseTickerEvent carries the latest ticker of one or more products.
+type coinbaseTickerEvent struct {
+	Type    string           `json:"type"`
+	Tickers []coinbaseTicker `json:"tickers"`
+}
+
+type coinbaseTicker struct {
+	ProductID       string `json:"product_id"`
+	BestBid         string `json:"best_bid"`
+	BestBidQuantity string `json:"best_bid_quantity"`
+	BestAsk         string `json:"best_ask"`
+	BestAskQuantity string `json:"best_ask_quantity"`
+}
+
+// StartStream connects to the Coinbase Advanced Trade WebSocket API and streams order book
+// ticks for all pairs. The local books are rebuilt from the level2 snapshots Coinbase
+// sends after every subscription; until a pair's snapshot arrives, its top of book comes
+// from the ticker channel. A gap in sequence numbers forces a reconnect and fresh snapshots.
+func (cb *CoinbaseClient) StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pairs []string) error {
+	const wsURL = "wss://advanced-trade-ws.coinbase.com"
+	symbols, err := newSymbolMap(cb.instruments, cb.GetName(), pairs)
+	if err != nil {
+		return err
+	}
+	backoff := time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			cb.logger.Info("CoinbaseClient: context cancelled, shutting down")
+			return nil
+		default:
+			cb.logger.Info("CoinbaseClient: connecting to WebSocket", "url", wsURL, "backoff", backoff)
+			c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			if err != nil {
+				cb.logger.Error("CoinbaseClient: WebSocket connection failed", "error", err)
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-cb.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+
+			// Reset backoff on successful connection
+			backoff = time.Second
+
+			// Subscribe to the books and tickers of every pair. Coinbase closes quiet level2
+			// connections, so the heartbeats channel keeps the connection alive.
+			if err := cb.subscribe(c, symbols.Natives()); err != nil {
+				cb.logger.Error("CoinbaseClient: failed to send subscription", "error", err)
+				if closeErr := c.Close(); closeErr != nil {
+					cb.logger.Warn("CoinbaseClient: failed to close connection", "error", closeErr)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-cb.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+			cb.logger.Info("CoinbaseClient: subscription sent successfully")
+
+			feed := newCoinbaseFeed(cb.logger, symbols)
+
+			// Handle incoming messages
+		messages:
+			for {
+				select {
+				case <-ctx.Done():
+					cb.logger.Info("CoinbaseClient: context cancelled, closing connection")
+					if closeErr := c.Close(); closeErr != nil {
+						cb.logger.Warn("CoinbaseClient: failed to close connection", "error", closeErr)
+					}
+					return nil
+				default:
+					_, message, err := c.ReadMessage()
+					receivedAt := cb.clock.Now()
+					if err != nil {
+						cb.logger.Error("CoinbaseClient: failed to read message", "error", err)
+						if closeErr := c.Close(); closeErr != nil {
+							cb.logger.Warn("CoinbaseClient: failed to close connection", "error", closeErr)
+						}
+						// Break out of message loop to trigger reconnection
+						break messages
+					}
+
+					// Parse the message
+					var msg coinbaseMessage
+					if err := json.Unmarshal(message, &msg); err != nil {
+						cb.logger.Warn("CoinbaseClient: failed to parse message", "error", err)
+						continue
+					}
+					if msg.Channel == "" {
+						// Errors are reported in a message without a channel
+						cb.logger.Warn("CoinbaseClient: received error message", "message", string(message))
+						continue
+					}
+
+					if msg.Channel == "subscriptions" {
+						cb.logger.Info("CoinbaseClient: subscription confirmed", "events", string(msg.Events))
+					}
+
+					ticks, err := feed.apply(msg)
+					if errors.Is(err, errSequenceGap) {
+						cb.logger.Warn("CoinbaseClient: message sequence gap, resyncing", "error", err)
+						if closeErr := c.Close(); closeErr != nil {
+							cb.logger.Warn("CoinbaseClient: failed to close connection", "error", closeErr)
+						}
+						break messages
+					}
+					if err != nil {
+						cb.logger.Warn("CoinbaseClient: failed to apply message", "channel", msg.Channel, "error", err)
+						continue
+					}
+					exchangeTime, _ := time.Parse(time.RFC3339Nano, msg.Timestamp)
+
+					for _, tick := range ticks {
+						tick.ExchangeTime = exchangeTime
+						tick.ReceivedAt = receivedAt
+						select {
+						case priceChan <- tick:
+							cb.logger.Debug("CoinbaseClient: sent price tick", "pair", tick.Pair, "bid", tick.Bid, "ask", tick.Ask,
+								"feedLatency", tick.ReceivedAt.Sub(tick.ExchangeTime))
+						case <-ctx.Done():
+							cb.logger.Info("CoinbaseClient: context cancelled while sending price tick")
+							if closeErr := c.Close(); closeErr != nil {
+								cb.logger.Warn("CoinbaseClient: failed to close connection", "error", closeErr)
+							}
+							return nil
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// subscribe sends one subscription per channel, since Coinbase accepts a single
+// channel per subscribe message.
+func (cb *CoinbaseClient) subscribe(c *websocket.Conn, products []string) error {
+	for _, channel := range []string{"heartbeats", "level2", "ticker"} {
+		subscription := map[string]interface{}{
+			"type":        "subscribe",
+			"product_ids": products,
+			"channel":     channel,
+		}
+		if err := c.WriteJSON(subscription); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// coinbaseFeed applies the messages of one connection to a local book per pair.
+type coinbaseFeed struct {
+	logger       *slog.Logger
+	symbols      symbolMap
+	books        map[string]*model.OrderBook
+	synced       map[string]bool // Pairs whose level2 snapshot has arrived
+	lastSequence int64
+}
+
+// newCoinbaseFeed creates a feed with empty books, expecting the first message of a
+// connection next.
+func newCoinbaseFeed(logger *slog.Logger, symbols symbolMap) *coinbaseFeed {
+	f := &coinbaseFeed{
+		logger:       logger,
+		symbols:      symbols,
+		books:        make(map[string]*model.OrderBook, len(symbols.pairs)),
+		synced:       make(map[string]bool, len(symbols.pairs)),
+		lastSequence: -1,
+	}
+	for _, pair := range symbols.pairs {
+		f.books[pair] = model.NewOrderBook("coinbase", pair)
+	}
+	return f
+}
+
+// apply checks the message follows the previous one and returns the ticks it produces.
+// A missed message may have been a book update, so a gap returns errSequenceGap and
+// the books can no longer be trusted.
+func (f *coinbaseFeed) apply(msg coinbaseMessage) ([]model.PriceTick, error) {
+	if msg.SequenceNum != f.lastSequence+1 {
+		return nil, fmt.Errorf("%w: expected %d, received %d", errSequenceGap, f.lastSequence+1, msg.SequenceNum)
+	}
+	f.lastSequence = msg.SequenceNum
+	switch msg.Channel {
+	case "l2_data":
+		return f.applyBookEvents(msg)
+	case "ticker":
+		return f.tickerTicks(msg)
+	default:
+		return nil, nil
+	}
+}
+
+// applyBookEvents applies the level2 snapshots and updates of a message to the local
+// books and returns a tick for every book that changed.
+func (f *coinbaseFeed) applyBookEvents(msg coinbaseMessage) ([]model.PriceTick, error) {
+	var events []coinbaseBookEvent
+	if err := json.Unmarshal(msg.Events, &events); err != nil {
+		return nil, err
+	}
+	var ticks []model.PriceTick
+	for _, event := range events {
+		pair, ok := f.symbols.Canonical(event.ProductID)
+		if !ok {
+			f.logger.Warn("CoinbaseClient: received data for unknown product", "product", event.ProductID)
+			continue
+		}
+		bids, asks, err := parseCoinbaseUpdates(event.Updates)
+		if err != nil {
+			return nil, err
+		}
+		book := f.books[pair]
+		switch event.Type {
+		case "snapshot":
+			book.ApplySnapshot(bids, asks, msg.SequenceNum)
+			f.synced[pair] = true
+		case "update":
+			if !f.synced[pair] {
+				continue
+			}
+			if err := book.ApplyDelta(bids, asks, msg.SequenceNum); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+		book.Truncate(maxBookLevels)
+		if tick, ok := book.Tick(bookDepth); ok {
+			ticks = append(ticks, tick)
+		}
+	}
+	return ticks, nil
+}
+
+// tickerTicks returns the top of book of the ticker events for pairs whose level2
+// snapshot has not arrived yet. Once a book is synced it is the more complete source.
+func (f *coinbaseFeed) tickerTicks(msg coinbaseMessage) ([]model.PriceTick, error) {
+	var events []coinbaseTickerEvent
+	if err := json.Unmarshal(msg.Events, &events); err != nil {
+		return nil, err
+	}
+	var ticks []model.PriceTick
+	for _, event := range events {
+		for _, ticker := range event.Tickers {
+			pair, ok := f.symbols.Canonical(ticker.ProductID)
+			if !ok || f.synced[pair] {
+				continue
+			}
+			bid, err := parseLevel(ticker.BestBid, ticker.BestBidQuantity)
+			if err != nil {
+				return nil, err
+			}
+			ask, err := parseLevel(ticker.BestAsk, ticker.BestAskQuantity)
+			if err != nil {
+				return nil, err
+			}
+			ticks = append(ticks, model.PriceTick{
+				Exchange: "coinbase",
+				Pair:     pair,
+				Bid:      bid.Price,
+				Ask:      ask.Price,
+				Bids:     []model.PriceLevel{bid},
+				Asks:     []model.PriceLevel{ask},
+				Sequence: msg.SequenceNum,
+			})
+		}
+	}
+	return ticks, nil
+}
+
+// parseCoinbaseUpdates splits level2 updates into bid and offer levels.
+func parseCoinbaseUpdates(updates []coinbaseBookUpdate) (bids, asks []model.PriceLevel, err error) {
+	for _, update := range updates {
+		level, err := parseLevel(update.PriceLevel, update.NewQuantity)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch update.Side {
+		case "bid":
+			bids = append(bids, level)
+		case "offer":
+			asks = append(asks, level)
+		default:
+			return nil, nil, fmt.Errorf("unknown book side: %q", update.Side)
+		}
+	}
+	return bids, asks, nil
+}
@@ -0,0 +1,87 @@
+package exchange
+
+import (
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"referee/internal/model"
+)
+
+func TestCoinbaseFeed(t *testing.T) {
+	symbols := symbolMap{
+		pairs:     []string{"BTC/EUR"},
+		native:    map[string]string{"BTC/EUR": "BTC-EUR"},
+		canonical: map[string]string{"BTC-EUR": "BTC/EUR"},
+	}
+	feed := newCoinbaseFeed(slog.New(slog.DiscardHandler), symbols)
+	message := func(channel string, sequence int64, events string) coinbaseMessage {
+		return coinbaseMessage{Channel: channel, SequenceNum: sequence, Events: json.RawMessage(events)}
+	}
+
+	t.Run("ticker is used until the snapshot arrives", func(t *testing.T) {
+		ticks, err := feed.apply(message("ticker", 0, `[{"type":"snapshot","tickers":[{"product_id":"BTC-EUR",
+			"best_bid":"60000","best_bid_quantity":"1","best_ask":"60010","best_ask_quantity":"2"}]}]`))
+		assert.NoError(t, err)
+		assert.Len(t, ticks, 1)
+		assert.Equal(t, 60000.0, ticks[0].Bid)
+		assert.Equal(t, 60010.0, ticks[0].Ask)
+	})
+
+	t.Run("update before the snapshot is ignored", func(t *testing.T) {
+		ticks, err := feed.apply(message("l2_data", 1, `[{"type":"update","product_id":"BTC-EUR",
+			"updates":[{"side":"bid","price_level":"60005","new_quantity":"1"}]}]`))
+		assert.NoError(t, err)
+		assert.Empty(t, ticks)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		ticks, err := feed.apply(message("l2_data", 2, `[{"type":"snapshot","product_id":"BTC-EUR","updates":[
+			{"side":"bid","price_level":"59990","new_quantity":"1"},
+			{"side":"bid","price_level":"60000","new_quantity":"0.5"},
+			{"side":"offer","price_level":"60010","new_quantity":"2"}]}]`))
+		assert.NoError(t, err)
+		assert.Len(t, ticks, 1)
+		assert.Equal(t, []model.PriceLevel{{Price: 60000, Size: 0.5}, {Price: 59990, Size: 1}}, ticks[0].Bids)
+		assert.Equal(t, int64(2), ticks[0].Sequence)
+	})
+
+	t.Run("update changes and removes levels", func(t *testing.T) {
+		ticks, err := feed.apply(message("l2_data", 3, `[{"type":"update","product_id":"BTC-EUR","updates":[
+			{"side":"bid","price_level":"60000","new_quantity":"0"},
+			{"side":"offer","price_level":"60008","new_quantity":"1"}]}]`))
+		assert.NoError(t, err)
+		assert.Len(t, ticks, 1)
+		assert.Equal(t, []model.PriceLevel{{Price: 59990, Size: 1}}, ticks[0].Bids)
+		assert.Equal(t, []model.PriceLevel{{Price: 60008, Size: 1}, {Price: 60010, Size: 2}}, ticks[0].Asks)
+	})
+
+	t.Run("ticker is ignored once the book is synced", func(t *testing.T) {
+		ticks, err := feed.apply(message("ticker", 4, `[{"type":"update","tickers":[{"product_id":"BTC-EUR",
+			"best_bid":"1","best_bid_quantity":"1","best_ask":"2","best_ask_quantity":"1"}]}]`))
+		assert.NoError(t, err)
+		assert.Empty(t, ticks)
+	})
+
+	t.Run("heartbeats advance the sequence", func(t *testing.T) {
+		ticks, err := feed.apply(message("heartbeats", 5, `[]`))
+		assert.NoError(t, err)
+		assert.Empty(t, ticks)
+	})
+
+	t.Run("sequence gap", func(t *testing.T) {
+		_, err := feed.apply(message("heartbeats", 7, `[]`))
+		assert.ErrorIs(t, err, errSequenceGap)
+
+		_, err = feed.apply(message("heartbeats", 5, `[]`))
+		assert.ErrorIs(t, err, errSequenceGap)
+	})
+
+	t.Run("unknown side", func(t *testing.T) {
+		_, err := feed.apply(message("l2_data", 6, `[{"type":"update","product_id":"BTC-EUR",
+			"updates":[{"side":"ask","price_level":"60000","new_quantity":"1"}]}]`))
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, errSequenceGap)
+	})
+}
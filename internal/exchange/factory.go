@@ -3,16 +3,29 @@ package exchange
 import (
 	"fmt"
 	"log/slog"
+	"referee/internal/clock"
 	"referee/internal/config"
+	"referee/internal/instrument"
 )
 
 // NewClient creates a new exchange client based on the given name and configuration.
-func NewClient(name string, logger *slog.Logger, cfg *config.ExchangeConfig) (ExchangeClient, error) {
+// Symbols are resolved through the instrument registry.
+func NewClient(name string, logger *slog.Logger, cfg *config.ExchangeConfig, clk clock.Clock, instruments *instrument.Registry) (ExchangeClient, error) {
 	switch name {
 	case "kraken":
-		return NewKrakenClient(logger), nil
+		return NewKrakenClient(logger, clk, instruments), nil
 	case "binance":
-		return NewBinanceClient(logger), nil
+		return NewBinanceClient(logger, clk, instruments), nil
+	case "coinbase":
+		return NewCoinbaseClient(logger, clk, instruments), nil
+	case "bitstamp":
+		return NewBitstampClient(logger, clk, instruments), nil
+	case "bitvavo":
+		return NewBitvavoClient(logger, clk, instruments), nil
+	case "okx":
+		return NewOKXClient(logger, clk, instruments), nil
+	case "bybit":
+		return NewBybitClient(logger, clk, instruments), nil
 	default:
 		return nil, fmt.Errorf("unknown exchange: %s", name)
 	}
@@ -0,0 +1,28 @@
+package exchange
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"referee/internal/clock"
+)
+
+// keepAlive sends payload on the connection every interval, for exchanges that close
+// connections without application-level pings. It must be the connection's only
+// writer once started, and stops when the context is cancelled or a write fails,
+// which happens once the connection has been closed.
+func keepAlive(ctx context.Context, logger *slog.Logger, clk clock.Clock, c *websocket.Conn, interval time.Duration, messageType int, payload []byte) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clk.After(interval):
+			if err := c.WriteMessage(messageType, payload); err != nil {
+				logger.Debug("Stopping keepalive pings", "error", err)
+				return
+			}
+		}
+	}
+}
@@ -2,32 +2,41 @@ package exchange
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
+	"strconv"
 	"time"
 
-	"encoding/json"
 	"github.com/gorilla/websocket"
+	"referee/internal/clock"
+	"referee/internal/instrument"
 	"referee/internal/model"
-	"strconv"
 )
 
 // KrakenClient implements the ExchangeClient interface for Kraken.
 type KrakenClient struct {
-	logger *slog.Logger
+	logger      *slog.Logger
+	clock       clock.Clock
+	instruments *instrument.Registry
 }
 
 // NewKrakenClient creates a new KrakenClient.
-func NewKrakenClient(logger *slog.Logger) *KrakenClient {
-	return &KrakenClient{logger: logger}
+func NewKrakenClient(logger *slog.Logger, clk clock.Clock, instruments *instrument.Registry) *KrakenClient {
+	return &KrakenClient{logger: logger, clock: clk, instruments: instruments}
 }
 
 func (k *KrakenClient) GetName() string {
 	return "kraken"
 }
 
-// StartStream connects to the Kraken WebSocket API and streams BTC/EUR price ticks.
-func (k *KrakenClient) StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pair string) error {
+// StartStream connects to the Kraken WebSocket API and streams order book ticks for all pairs.
+// The local books are rebuilt from the snapshots Kraken sends after every (re)subscription.
+func (k *KrakenClient) StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pairs []string) error {
 	const wsURL = "wss://ws.kraken.com"
+	symbols, err := newSymbolMap(k.instruments, k.GetName(), pairs)
+	if err != nil {
+		return err
+	}
 	backoff := time.Second
 	for {
 		select {
@@ -42,7 +51,7 @@ func (k *KrakenClient) StartStream(ctx context.Context, priceChan chan<- model.P
 				select {
 				case <-ctx.Done():
 					return nil
-				case <-time.After(backoff):
+				case <-k.clock.After(backoff):
 					backoff *= 2
 					if backoff > 16*time.Second {
 						backoff = 16 * time.Second
@@ -54,33 +63,40 @@ func (k *KrakenClient) StartStream(ctx context.Context, priceChan chan<- model.P
 			// Reset backoff on successful connection
 			backoff = time.Second
 
-			// Send subscription message for BTC/EUR ticker
+			// Send subscription message for the order books of every pair
 			subscription := map[string]interface{}{
 				"event": "subscribe",
-				"pair":  []string{"XBT/EUR"},
-				"subscription": map[string]string{
-					"name": "ticker",
+				"pair":  symbols.Natives(),
+				"subscription": map[string]interface{}{
+					"name":  "book",
+					"depth": bookDepth,
 				},
 			}
-							if err := c.WriteJSON(subscription); err != nil {
-					k.logger.Error("KrakenClient: failed to send subscription", "error", err)
-					if closeErr := c.Close(); closeErr != nil {
-						k.logger.Warn("KrakenClient: failed to close connection", "error", closeErr)
-					}
-					select {
-					case <-ctx.Done():
-						return nil
-					case <-time.After(backoff):
-						backoff *= 2
-						if backoff > 16*time.Second {
-							backoff = 16 * time.Second
-						}
+			if err := c.WriteJSON(subscription); err != nil {
+				k.logger.Error("KrakenClient: failed to send subscription", "error", err)
+				if closeErr := c.Close(); closeErr != nil {
+					k.logger.Warn("KrakenClient: failed to close connection", "error", closeErr)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-k.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
 					}
-					continue
 				}
+				continue
+			}
 			k.logger.Info("KrakenClient: subscription sent successfully")
 
+			books := make(map[string]*model.OrderBook, len(pairs))
+			for _, pair := range pairs {
+				books[pair] = model.NewOrderBook("kraken", pair)
+			}
+
 			// Handle incoming messages
+		messages:
 			for {
 				select {
 				case <-ctx.Done():
@@ -91,77 +107,156 @@ func (k *KrakenClient) StartStream(ctx context.Context, priceChan chan<- model.P
 					return nil
 				default:
 					_, message, err := c.ReadMessage()
+					receivedAt := k.clock.Now()
 					if err != nil {
 						k.logger.Error("KrakenClient: failed to read message", "error", err)
 						if closeErr := c.Close(); closeErr != nil {
 							k.logger.Warn("KrakenClient: failed to close connection", "error", closeErr)
 						}
 						// Break out of message loop to trigger reconnection
-						break
+						break messages
 					}
 
 					// Parse the message - Kraken sends both objects and arrays
 					var msgObj map[string]interface{}
 					var msgArray []interface{}
-					
-					// Try to parse as object first (for subscription confirmations)
+
+					// Try to parse as object first (for subscription confirmations and heartbeats)
 					if err := json.Unmarshal(message, &msgObj); err == nil {
 						// Handle subscription confirmation
 						if event, ok := msgObj["event"].(string); ok && event == "subscriptionStatus" {
-							k.logger.Info("KrakenClient: subscription confirmed")
-							continue
+							if status, _ := msgObj["status"].(string); status == "error" {
+								k.logger.Warn("KrakenClient: subscription rejected", "pair", msgObj["pair"], "error", msgObj["errorMessage"])
+								continue
+							}
+							k.logger.Info("KrakenClient: subscription confirmed", "pair", msgObj["pair"])
 						}
-						// If it's an object but not a subscription confirmation, skip it
 						continue
 					}
-					
-					// Try to parse as array (for ticker data: [channelID, tickerData, pair, channelName])
+
+					// Try to parse as array (for book data: [channelID, bookData..., channelName, pair])
 					if err := json.Unmarshal(message, &msgArray); err != nil {
 						k.logger.Warn("KrakenClient: failed to parse message", "error", err)
 						continue
 					}
-					
-					// Check if it's a ticker array with at least 2 elements
-					if len(msgArray) >= 2 {
-						if tickerData, ok := msgArray[1].(map[string]interface{}); ok {
-							// Extract bid and ask prices
-							if bidStr, ok := tickerData["b"].([]interface{}); ok && len(bidStr) > 0 {
-								if askStr, ok := tickerData["a"].([]interface{}); ok && len(askStr) > 0 {
-									bid, err := strconv.ParseFloat(bidStr[0].(string), 64)
-									if err != nil {
-										k.logger.Warn("KrakenClient: failed to parse bid price", "error", err)
-										continue
-									}
-									ask, err := strconv.ParseFloat(askStr[0].(string), 64)
-									if err != nil {
-										k.logger.Warn("KrakenClient: failed to parse ask price", "error", err)
-										continue
-									}
-
-									// Create and send price tick
-									tick := model.PriceTick{
-										Exchange: "kraken",
-										Pair:     "BTC/EUR",
-										Bid:      bid,
-										Ask:      ask,
-									}
-
-									select {
-									case priceChan <- tick:
-										k.logger.Debug("KrakenClient: sent price tick", "bid", bid, "ask", ask)
-									case <-ctx.Done():
-										k.logger.Info("KrakenClient: context cancelled while sending price tick")
-										if closeErr := c.Close(); closeErr != nil {
-											k.logger.Warn("KrakenClient: failed to close connection", "error", closeErr)
-										}
-										return nil
-									}
-								}
-							}
+					if len(msgArray) < 4 {
+						continue
+					}
+					native, _ := msgArray[len(msgArray)-1].(string)
+					pair, ok := symbols.Canonical(native)
+					if !ok {
+						k.logger.Warn("KrakenClient: received data for unknown pair", "pair", native)
+						continue
+					}
+					book := books[pair]
+
+					// Updates carry the asks and bids in up to two separate objects
+					exchangeTime, err := k.applyBookMessage(book, msgArray[1:len(msgArray)-2])
+					if err != nil {
+						k.logger.Warn("KrakenClient: failed to apply book update", "error", err)
+						continue
+					}
+
+					tick, ok := book.Tick(bookDepth)
+					if !ok {
+						continue
+					}
+					tick.ExchangeTime = exchangeTime
+					tick.ReceivedAt = receivedAt
+
+					select {
+					case priceChan <- tick:
+						k.logger.Debug("KrakenClient: sent price tick", "pair", pair, "bid", tick.Bid, "ask", tick.Ask,
+							"feedLatency", tick.ReceivedAt.Sub(tick.ExchangeTime))
+					case <-ctx.Done():
+						k.logger.Info("KrakenClient: context cancelled while sending price tick")
+						if closeErr := c.Close(); closeErr != nil {
+							k.logger.Warn("KrakenClient: failed to close connection", "error", closeErr)
 						}
+						return nil
 					}
 				}
 			}
 		}
 	}
 }
+
+// applyBookMessage applies the payload objects of a Kraken book message to the local book
+// and returns the latest level timestamp it contained.
+// Snapshots use the "as"/"bs" keys, updates use "a"/"b". Kraken does not send sequence
+// numbers on v1 book channels, so a local counter is used instead.
+func (k *KrakenClient) applyBookMessage(book *model.OrderBook, payloads []interface{}) (time.Time, error) {
+	var exchangeTime time.Time
+	for _, payload := range payloads {
+		data, ok := payload.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if rawAsks, ok := data["as"].([]interface{}); ok {
+			rawBids, _ := data["bs"].([]interface{})
+			asks, err := parseLevels(rawAsks)
+			if err != nil {
+				return time.Time{}, err
+			}
+			bids, err := parseLevels(rawBids)
+			if err != nil {
+				return time.Time{}, err
+			}
+			exchangeTime = latestKrakenTimestamp(exchangeTime, rawAsks, rawBids)
+			book.ApplySnapshot(bids, asks, book.Sequence+1)
+			continue
+		}
+
+		var bids, asks []model.PriceLevel
+		if rawAsks, ok := data["a"].([]interface{}); ok {
+			levels, err := parseLevels(rawAsks)
+			if err != nil {
+				return time.Time{}, err
+			}
+			asks = levels
+			exchangeTime = latestKrakenTimestamp(exchangeTime, rawAsks)
+		}
+		if rawBids, ok := data["b"].([]interface{}); ok {
+			levels, err := parseLevels(rawBids)
+			if err != nil {
+				return time.Time{}, err
+			}
+			bids = levels
+			exchangeTime = latestKrakenTimestamp(exchangeTime, rawBids)
+		}
+		if err := book.ApplyDelta(bids, asks, book.Sequence+1); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	// Kraken expects clients to drop levels that fall outside the subscribed depth
+	book.Truncate(bookDepth)
+	return exchangeTime, nil
+}
+
+// latestKrakenTimestamp returns the most recent of current and the level timestamps,
+// which Kraken sends as fractional unix seconds in the third element of each level.
+func latestKrakenTimestamp(current time.Time, sides ...[]interface{}) time.Time {
+	for _, side := range sides {
+		for _, entry := range side {
+			fields, ok := entry.([]interface{})
+			if !ok || len(fields) < 3 {
+				continue
+			}
+			tsStr, ok := fields[2].(string)
+			if !ok {
+				continue
+			}
+			seconds, err := strconv.ParseFloat(tsStr, 64)
+			if err != nil {
+				continue
+			}
+			ts := time.UnixMicro(int64(seconds * 1e6))
+			if ts.After(current) {
+				current = ts
+			}
+		}
+	}
+	return current
+}
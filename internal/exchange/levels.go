@@ -0,0 +1,54 @@
+package exchange
+
+import (
+	"fmt"
+	"strconv"
+
+	"referee/internal/model"
+)
+
+// bookDepth is the number of order book levels each client forwards per side.
+const bookDepth = 25
+
+// maxBookLevels is how many levels per side a local book keeps when the exchange sends
+// updates for its whole depth, which would be costly to sort on every update.
+const maxBookLevels = 200
+
+// parseLevels converts exchange level arrays of the form ["price", "size", ...]
+// into price levels. Any trailing elements (timestamps, flags) are ignored.
+func parseLevels(raw []interface{}) ([]model.PriceLevel, error) {
+	levels := make([]model.PriceLevel, 0, len(raw))
+	for _, entry := range raw {
+		fields, ok := entry.([]interface{})
+		if !ok || len(fields) < 2 {
+			return nil, fmt.Errorf("malformed price level: %v", entry)
+		}
+		priceStr, ok := fields[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("malformed price: %v", fields[0])
+		}
+		sizeStr, ok := fields[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("malformed size: %v", fields[1])
+		}
+		level, err := parseLevel(priceStr, sizeStr)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// parseLevel converts a price and size sent as strings into a price level.
+func parseLevel(priceStr, sizeStr string) (model.PriceLevel, error) {
+	price, err := strconv.ParseFloat(priceStr, 64)
+	if err != nil {
+		return model.PriceLevel{}, err
+	}
+	size, err := strconv.ParseFloat(sizeStr, 64)
+	if err != nil {
+		return model.PriceLevel{}, err
+	}
+	return model.PriceLevel{Price: price, Size: size}, nil
+}
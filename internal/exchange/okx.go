@@ -0,0 +1,294 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"referee/internal/clock"
+	"referee/internal/instrument"
+	"referee/internal/model"
+)
+
+// okxPingInterval is how often a ping is sent. OKX closes connections that have been
+// silent for 30 seconds.
+const okxPingInterval = 20 * time.Second
+
+// OKXClient implements the ExchangeClient interface for OKX.
+type OKXClient struct {
+	logger      *slog.Logger
+	clock       clock.Clock
+	instruments *instrument.Registry
+}
+
+// NewOKXClient creates a new OKXClient.
+func NewOKXClient(logger *slog.Logger, clk clock.Clock, instruments *instrument.Registry) *OKXClient {
+	return &OKXClient{logger: logger, clock: clk, instruments: instruments}
+}
+
+func (o *OKXClient) GetName() string {
+	return "okx"
+}
+
+// okxMessage is a push or event of the v5 public WebSocket API.
+type okxMessage struct {
+	Event string `json:"event"`
+	Code  string `json:"code"`
+	Msg   string `json:"msg"`
+	Arg   struct {
+		Channel string `json:"channel"`
+		InstID  string `json:"instId"`
+	} `json:"arg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// okxBook is a push of the books5 channel, which always carries the top five levels of
+// each side. Being full snapshots, books5 pushes carry no checksum; seqId orders them.
+type okxBook struct {
+	Bids  []interface{} `json:"bids"`
+	Asks  []interface{} `json:"asks"`
+	Ts    string        `json:"ts"`
+	SeqID int64         `json:"seqId"`
+}
+
+// okxTicker is a push of the tickers channel.
+type okxTicker struct {
+	BidPx string `json:"bidPx"`
+	BidSz string `json:"bidSz"`
+	AskPx string `json:"askPx"`
+	AskSz string `json:"askSz"`
+	Ts    string `json:"ts"`
+}
+
+// StartStream connects to the OKX WebSocket API and streams order book ticks for all pairs.
+// Every books5 push is a full snapshot of the top five levels. Until a pair's first book
+// arrives, its top of book comes from the tickers channel. A text "ping" keeps the
+// connection open while the books are quiet.
+func (o *OKXClient) StartStream(ctx context.Context, priceChan chan<- model.PriceTick, pairs []string) error {
+	const wsURL = "wss://ws.okx.com:8443/ws/v5/public"
+	symbols, err := newSymbolMap(o.instruments, o.GetName(), pairs)
+	if err != nil {
+		return err
+	}
+	backoff := time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			o.logger.Info("OKXClient: context cancelled, shutting down")
+			return nil
+		default:
+			o.logger.Info("OKXClient: connecting to WebSocket", "url", wsURL, "backoff", backoff)
+			c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+			if err != nil {
+				o.logger.Error("OKXClient: WebSocket connection failed", "error", err)
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-o.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+
+			// Reset backoff on successful connection
+			backoff = time.Second
+
+			// Send subscription message for the books and tickers of every pair
+			args := make([]map[string]string, 0, 2*len(pairs))
+			for _, native := range symbols.Natives() {
+				args = append(args,
+					map[string]string{"channel": "books5", "instId": native},
+					map[string]string{"channel": "tickers", "instId": native},
+				)
+			}
+			subscription := map[string]interface{}{
+				"op":   "subscribe",
+				"args": args,
+			}
+			if err := c.WriteJSON(subscription); err != nil {
+				o.logger.Error("OKXClient: failed to send subscription", "error", err)
+				if closeErr := c.Close(); closeErr != nil {
+					o.logger.Warn("OKXClient: failed to close connection", "error", closeErr)
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-o.clock.After(backoff):
+					backoff *= 2
+					if backoff > 16*time.Second {
+						backoff = 16 * time.Second
+					}
+				}
+				continue
+			}
+			o.logger.Info("OKXClient: subscription sent successfully")
+			go keepAlive(ctx, o.logger, o.clock, c, okxPingInterval, websocket.TextMessage, []byte("ping"))
+
+			books := make(map[string]*model.OrderBook, len(pairs))
+			synced := make(map[string]bool, len(pairs))
+			for _, pair := range pairs {
+				books[pair] = model.NewOrderBook("okx", pair)
+			}
+
+			// Handle incoming messages
+		messages:
+			for {
+				select {
+				case <-ctx.Done():
+					o.logger.Info("OKXClient: context cancelled, closing connection")
+					if closeErr := c.Close(); closeErr != nil {
+						o.logger.Warn("OKXClient: failed to close connection", "error", closeErr)
+					}
+					return nil
+				default:
+					_, message, err := c.ReadMessage()
+					receivedAt := o.clock.Now()
+					if err != nil {
+						o.logger.Error("OKXClient: failed to read message", "error", err)
+						if closeErr := c.Close(); closeErr != nil {
+							o.logger.Warn("OKXClient: failed to close connection", "error", closeErr)
+						}
+						// Break out of message loop to trigger reconnection
+						break messages
+					}
+					if string(message) == "pong" {
+						continue
+					}
+
+					// Parse the message
+					var msg okxMessage
+					if err := json.Unmarshal(message, &msg); err != nil {
+						o.logger.Warn("OKXClient: failed to parse message", "error", err)
+						continue
+					}
+					switch msg.Event {
+					case "subscribe":
+						o.logger.Info("OKXClient: subscription confirmed", "channel", msg.Arg.Channel, "instId", msg.Arg.InstID)
+						continue
+					case "error":
+						o.logger.Warn("OKXClient: received error", "code", msg.Code, "error", msg.Msg)
+						continue
+					case "":
+						// Data pushes carry no event
+					default:
+						continue
+					}
+					pair, ok := symbols.Canonical(msg.Arg.InstID)
+					if !ok {
+						o.logger.Warn("OKXClient: received data for unknown instrument", "instId", msg.Arg.InstID)
+						continue
+					}
+
+					var tick model.PriceTick
+					var exchangeTime time.Time
+					switch msg.Arg.Channel {
+					case "books5":
+						exchangeTime, err = applyOKXBook(books[pair], msg.Data)
+						if errors.Is(err, model.ErrStaleUpdate) {
+							o.logger.Debug("OKXClient: dropped duplicate book snapshot", "pair", pair, "seqId", books[pair].Sequence)
+							continue
+						}
+						if err != nil {
+							o.logger.Warn("OKXClient: failed to apply book snapshot", "error", err)
+							continue
+						}
+						synced[pair] = true
+						tick, ok = books[pair].Tick(bookDepth)
+					case "tickers":
+						// The book is the more complete source once it has arrived
+						if synced[pair] {
+							continue
+						}
+						tick, exchangeTime, ok = o.tickerTick(pair, msg.Data)
+					default:
+						continue
+					}
+					if !ok {
+						continue
+					}
+					tick.ExchangeTime = exchangeTime
+					tick.ReceivedAt = receivedAt
+
+					select {
+					case priceChan <- tick:
+						o.logger.Debug("OKXClient: sent price tick", "pair", pair, "bid", tick.Bid, "ask", tick.Ask,
+							"feedLatency", tick.ReceivedAt.Sub(tick.ExchangeTime))
+					case <-ctx.Done():
+						o.logger.Info("OKXClient: context cancelled while sending price tick")
+						if closeErr := c.Close(); closeErr != nil {
+							o.logger.Warn("OKXClient: failed to close connection", "error", closeErr)
+						}
+						return nil
+					}
+				}
+			}
+		}
+	}
+}
+
+// applyOKXBook replaces the book with the latest books5 snapshot and returns its
+// timestamp. A repeat of the book's seqId returns model.ErrStaleUpdate and leaves the
+// book unchanged. A lower seqId is taken as a new baseline, since OKX resets the IDs
+// during maintenance without dropping the connection.
+func applyOKXBook(book *model.OrderBook, data json.RawMessage) (time.Time, error) {
+	var snapshots []okxBook
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return time.Time{}, err
+	}
+	var exchangeTime time.Time
+	for _, snapshot := range snapshots {
+		if snapshot.SeqID == book.Sequence {
+			return time.Time{}, model.ErrStaleUpdate
+		}
+		bids, err := parseLevels(snapshot.Bids)
+		if err != nil {
+			return time.Time{}, err
+		}
+		asks, err := parseLevels(snapshot.Asks)
+		if err != nil {
+			return time.Time{}, err
+		}
+		book.ApplySnapshot(bids, asks, snapshot.SeqID)
+		exchangeTime = okxTime(snapshot.Ts)
+	}
+	return exchangeTime, nil
+}
+
+// tickerTick builds a tick carrying only the top of book of the latest ticker push.
+func (o *OKXClient) tickerTick(pair string, data json.RawMessage) (model.PriceTick, time.Time, bool) {
+	var tickers []okxTicker
+	if err := json.Unmarshal(data, &tickers); err != nil || len(tickers) == 0 {
+		return model.PriceTick{}, time.Time{}, false
+	}
+	ticker := tickers[len(tickers)-1]
+	bid, bidErr := parseLevel(ticker.BidPx, ticker.BidSz)
+	ask, askErr := parseLevel(ticker.AskPx, ticker.AskSz)
+	if bidErr != nil || askErr != nil {
+		return model.PriceTick{}, time.Time{}, false
+	}
+	return model.PriceTick{
+		Exchange: "okx",
+		Pair:     pair,
+		Bid:      bid.Price,
+		Ask:      ask.Price,
+		Bids:     []model.PriceLevel{bid},
+		Asks:     []model.PriceLevel{ask},
+	}, okxTime(ticker.Ts), true
+}
+
+// okxTime converts a millisecond timestamp sent as a string, returning the zero time
+// if it cannot be parsed.
+func okxTime(ts string) time.Time {
+	millis, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(millis)
+}
@@ -0,0 +1,56 @@
+package exchange
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"referee/internal/model"
+)
+
+func TestApplyOKXBook(t *testing.T) {
+	book := model.NewOrderBook("okx", "BTC/EUR")
+
+	t.Run("snapshot replaces the book", func(t *testing.T) {
+		ts, err := applyOKXBook(book, json.RawMessage(`[{"bids":[["60000","1","0","2"]],"asks":[["60010","2","0","1"]],
+			"ts":"1704067200000","seqId":100}]`))
+		assert.NoError(t, err)
+		assert.True(t, ts.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+		assert.Equal(t, int64(100), book.Sequence)
+
+		_, err = applyOKXBook(book, json.RawMessage(`[{"bids":[["60005","3","0","1"]],"asks":[["60008","1","0","1"]],
+			"ts":"1704067200100","seqId":105}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, []model.PriceLevel{{Price: 60005, Size: 3}}, book.Bids(0))
+		assert.Equal(t, []model.PriceLevel{{Price: 60008, Size: 1}}, book.Asks(0))
+		assert.Equal(t, int64(105), book.Sequence)
+	})
+
+	t.Run("duplicate seqId is dropped", func(t *testing.T) {
+		_, err := applyOKXBook(book, json.RawMessage(`[{"bids":[["1","1","0","1"]],"asks":[["2","1","0","1"]],
+			"ts":"1704067200000","seqId":105}]`))
+		assert.ErrorIs(t, err, model.ErrStaleUpdate)
+		assert.Equal(t, []model.PriceLevel{{Price: 60005, Size: 3}}, book.Bids(0))
+		assert.Equal(t, int64(105), book.Sequence)
+	})
+
+	t.Run("lower seqId after a reset is a new baseline", func(t *testing.T) {
+		_, err := applyOKXBook(book, json.RawMessage(`[{"bids":[["59000","1","0","1"]],"asks":[["59010","1","0","1"]],
+			"ts":"1704067300000","seqId":3}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, []model.PriceLevel{{Price: 59000, Size: 1}}, book.Bids(0))
+		assert.Equal(t, int64(3), book.Sequence)
+
+		_, err = applyOKXBook(book, json.RawMessage(`[{"bids":[["59005","2","0","1"]],"asks":[["59010","1","0","1"]],
+			"ts":"1704067300100","seqId":4}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, []model.PriceLevel{{Price: 59005, Size: 2}}, book.Bids(0))
+	})
+
+	t.Run("malformed level", func(t *testing.T) {
+		_, err := applyOKXBook(book, json.RawMessage(`[{"bids":[["x","1"]],"asks":[],"ts":"1","seqId":200}]`))
+		assert.Error(t, err)
+		assert.Equal(t, int64(4), book.Sequence)
+	})
+}
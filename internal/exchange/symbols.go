@@ -0,0 +1,51 @@
+package exchange
+
+import (
+	"referee/internal/instrument"
+)
+
+// symbolMap caches the registry's translation between canonical pairs ("BTC/EUR")
+// and one exchange's native symbols for the pairs being streamed.
+type symbolMap struct {
+	pairs     []string
+	native    map[string]string
+	canonical map[string]string
+}
+
+// newSymbolMap resolves the native symbol of every pair on the exchange through the registry.
+func newSymbolMap(registry *instrument.Registry, exchange string, pairs []string) (symbolMap, error) {
+	m := symbolMap{
+		native:    make(map[string]string, len(pairs)),
+		canonical: make(map[string]string, len(pairs)),
+	}
+	for _, pair := range pairs {
+		native, err := registry.Native(exchange, pair)
+		if err != nil {
+			return symbolMap{}, err
+		}
+		m.pairs = append(m.pairs, pair)
+		m.native[pair] = native
+		m.canonical[native] = pair
+	}
+	return m, nil
+}
+
+// Native returns the exchange symbol for a canonical pair.
+func (m symbolMap) Native(pair string) string {
+	return m.native[pair]
+}
+
+// Canonical returns the canonical pair for an exchange symbol.
+func (m symbolMap) Canonical(native string) (string, bool) {
+	pair, ok := m.canonical[native]
+	return pair, ok
+}
+
+// Natives returns the exchange symbols in the order the pairs were configured.
+func (m symbolMap) Natives() []string {
+	natives := make([]string, 0, len(m.pairs))
+	for _, pair := range m.pairs {
+		natives = append(natives, m.native[pair])
+	}
+	return natives
+}
@@ -0,0 +1,153 @@
+package fees
+
+import (
+	"sort"
+	"time"
+
+	"referee/internal/clock"
+	"referee/internal/config"
+)
+
+// volumeWindow is the period exchanges use to determine the fee tier.
+const volumeWindow = 30 * 24 * time.Hour
+
+// Tier is one volume tier of a fee schedule, with the taker fee in percent.
+type Tier struct {
+	MinVolumeEUR float64
+	TakerPercent float64
+}
+
+// Schedule holds an exchange's fee tiers, sorted by increasing volume. Base applies
+// to volumes below the lowest tier.
+type Schedule struct {
+	Tiers           []Tier
+	Base            Tier
+	DiscountPercent float64
+	Overrides       map[string][]Tier // Canonical pair -> tiers replacing the default ones
+}
+
+// NewSchedule creates a schedule from the exchange configuration. TakerFeePercent is
+// charged below the lowest tier, or at any volume if the exchange has no tiers.
+func NewSchedule(cfg config.ExchangeConfig) Schedule {
+	schedule := Schedule{
+		Tiers:           tiers(cfg.FeeSchedule.Tiers),
+		Base:            Tier{TakerPercent: cfg.TakerFeePercent},
+		DiscountPercent: cfg.FeeSchedule.DiscountPercent,
+		Overrides:       make(map[string][]Tier, len(cfg.FeeSchedule.Overrides)),
+	}
+	for _, override := range cfg.FeeSchedule.Overrides {
+		schedule.Overrides[override.Pair] = tiers(override.Tiers)
+	}
+	return schedule
+}
+
+func tiers(configured []config.FeeTierConfig) []Tier {
+	result := make([]Tier, 0, len(configured))
+	for _, tier := range configured {
+		result = append(result, Tier{
+			MinVolumeEUR: tier.MinVolumeEUR,
+			TakerPercent: tier.TakerFeePercent,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].MinVolumeEUR < result[j].MinVolumeEUR })
+	return result
+}
+
+// Tier returns the tier that applies to the pair at the given 30-day volume, or Base
+// if the volume is below every tier.
+func (s Schedule) Tier(pair string, volumeEUR float64) Tier {
+	tiers := s.Tiers
+	if override, ok := s.Overrides[pair]; ok && len(override) > 0 {
+		tiers = override
+	}
+	tier := s.Base
+	for _, candidate := range tiers {
+		if candidate.MinVolumeEUR > volumeEUR {
+			break
+		}
+		tier = candidate
+	}
+	return tier
+}
+
+// Rate returns the taker fee for the pair as a fraction of the notional, after any
+// discount.
+func (s Schedule) Rate(pair string, volumeEUR float64) float64 {
+	return s.Tier(pair, volumeEUR).TakerPercent / 100 * (1 - s.DiscountPercent/100)
+}
+
+// Model applies each exchange's fee schedule based on the simulated trading volume
+// of the last 30 days.
+type Model struct {
+	clock     clock.Clock
+	schedules map[string]Schedule
+	volumes   map[string]*rollingVolume
+}
+
+// NewModel creates a fee model for every configured exchange.
+func NewModel(cfg *config.Config, clk clock.Clock) *Model {
+	m := &Model{
+		clock:     clk,
+		schedules: make(map[string]Schedule, len(cfg.Exchanges)),
+		volumes:   make(map[string]*rollingVolume, len(cfg.Exchanges)),
+	}
+	for exchange, exchangeCfg := range cfg.Exchanges {
+		m.schedules[exchange] = NewSchedule(exchangeCfg)
+	}
+	return m
+}
+
+// TakerRate returns the taker fee for the pair on the exchange as a fraction of the notional.
+func (m *Model) TakerRate(exchange, pair string) float64 {
+	return m.schedules[exchange].Rate(pair, m.Volume(exchange))
+}
+
+// Record adds traded notional, in EUR, to the exchange's rolling volume.
+func (m *Model) Record(exchange string, volumeEUR float64) {
+	volume, ok := m.volumes[exchange]
+	if !ok {
+		volume = &rollingVolume{}
+		m.volumes[exchange] = volume
+	}
+	volume.add(m.clock.Now(), volumeEUR)
+}
+
+// Volume returns the exchange's traded notional over the last 30 days, in EUR.
+func (m *Model) Volume(exchange string) float64 {
+	volume, ok := m.volumes[exchange]
+	if !ok {
+		return 0
+	}
+	return volume.total(m.clock.Now())
+}
+
+// rollingVolume sums traded notional over the volume window.
+type rollingVolume struct {
+	fills []volumeFill
+	sum   float64
+}
+
+type volumeFill struct {
+	at     time.Time
+	amount float64
+}
+
+func (v *rollingVolume) add(at time.Time, amount float64) {
+	v.fills = append(v.fills, volumeFill{at: at, amount: amount})
+	v.sum += amount
+}
+
+// total drops fills that have left the window and returns the sum of the rest.
+func (v *rollingVolume) total(now time.Time) float64 {
+	cutoff := now.Add(-volumeWindow)
+	expired := 0
+	for expired < len(v.fills) && !v.fills[expired].at.After(cutoff) {
+		v.sum -= v.fills[expired].amount
+		expired++
+	}
+	v.fills = v.fills[expired:]
+	if len(v.fills) == 0 {
+		v.sum = 0 // Avoid accumulating floating point error
+	}
+	return v.sum
+}
@@ -0,0 +1,73 @@
+package fees
+
+import (
+	"testing"
+	"time"
+
+	"referee/internal/clock"
+	"referee/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModel(t *testing.T) {
+	cfg := &config.Config{
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken": {TakerFeePercent: 0.26},
+			"binance": {
+				TakerFeePercent: 0.1,
+				FeeSchedule: config.FeeScheduleConfig{
+					Tiers: []config.FeeTierConfig{
+						{MinVolumeEUR: 1000000, TakerFeePercent: 0.09},
+						{MinVolumeEUR: 0, TakerFeePercent: 0.1},
+						{MinVolumeEUR: 5000000, TakerFeePercent: 0.08},
+					},
+					DiscountPercent: 25,
+					Overrides: []config.FeeOverrideConfig{
+						{Pair: "BTC/USDT", Tiers: []config.FeeTierConfig{{}}},
+					},
+				},
+			},
+		},
+	}
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	model := NewModel(cfg, clk)
+
+	// Exchanges without a schedule charge the flat taker fee
+	assert.InDelta(t, 0.0026, model.TakerRate("kraken", "BTC/EUR"), 1e-12)
+
+	// The discount applies to every tier
+	assert.InDelta(t, 0.00075, model.TakerRate("binance", "BTC/EUR"), 1e-12)
+	assert.Equal(t, 0.0, model.TakerRate("binance", "BTC/USDT"))
+
+	// Volume moves the exchange into a cheaper tier
+	model.Record("binance", 600000)
+	clk.Advance(24 * time.Hour)
+	model.Record("binance", 600000)
+	assert.Equal(t, 1200000.0, model.Volume("binance"))
+	assert.InDelta(t, 0.000675, model.TakerRate("binance", "BTC/EUR"), 1e-12)
+
+	// ...and back out once the volume leaves the 30-day window
+	clk.Advance(29 * 24 * time.Hour)
+	assert.Equal(t, 600000.0, model.Volume("binance"))
+	assert.InDelta(t, 0.00075, model.TakerRate("binance", "BTC/EUR"), 1e-12)
+	assert.Equal(t, 0.0, model.Volume("kraken"))
+}
+
+func TestScheduleBelowLowestTier(t *testing.T) {
+	schedule := NewSchedule(config.ExchangeConfig{
+		TakerFeePercent: 0.26,
+		FeeSchedule: config.FeeScheduleConfig{
+			Tiers: []config.FeeTierConfig{
+				{MinVolumeEUR: 50000, TakerFeePercent: 0.24},
+				{MinVolumeEUR: 100000, TakerFeePercent: 0.22},
+			},
+		},
+	})
+
+	// The first tier only applies once its volume is reached
+	assert.InDelta(t, 0.0026, schedule.Rate("BTC/EUR", 0), 1e-12)
+	assert.InDelta(t, 0.0026, schedule.Rate("BTC/EUR", 49999), 1e-12)
+	assert.InDelta(t, 0.0024, schedule.Rate("BTC/EUR", 50000), 1e-12)
+	assert.InDelta(t, 0.0022, schedule.Rate("BTC/EUR", 150000), 1e-12)
+}
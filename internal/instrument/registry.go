@@ -0,0 +1,217 @@
+package instrument
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"referee/internal/config"
+)
+
+// Instrument is a canonical trading pair such as BTC/EUR.
+type Instrument struct {
+	Symbol    string
+	Base      string
+	Quote     string
+	Exchanges map[string]Listing
+}
+
+// Listing describes how an instrument trades on one exchange.
+type Listing struct {
+	Symbol      string  // Native exchange symbol, e.g. "XBT/EUR" on Kraken
+	TickSize    float64 // Minimum price increment
+	LotSize     float64 // Minimum quantity increment of the base asset
+	MinNotional float64 // Minimum order value in the quote asset
+}
+
+// Convention describes how an exchange spells symbols that are not configured explicitly.
+type Convention struct {
+	Separator string
+	Aliases   map[string]string // Canonical asset code -> exchange asset code
+}
+
+// defaultConventions holds the symbol conventions of the supported exchanges.
+var defaultConventions = map[string]Convention{
+	"kraken":   {Separator: "/", Aliases: map[string]string{"BTC": "XBT"}},
+	"binance":  {Separator: ""},
+	"coinbase": {Separator: "-"},
+	"bitstamp": {Separator: ""},
+	"bitvavo":  {Separator: "-"},
+	"okx":      {Separator: "-"},
+	"bybit":    {Separator: ""},
+}
+
+// Registry resolves canonical instruments and their native exchange symbols.
+type Registry struct {
+	instruments map[string]Instrument
+	conventions map[string]Convention
+}
+
+// NewRegistry creates a registry from the given instruments and exchange conventions.
+// Exchanges without a convention use upper-case symbols joined by a slash.
+func NewRegistry(instruments []Instrument, conventions map[string]Convention) *Registry {
+	r := &Registry{
+		instruments: make(map[string]Instrument, len(instruments)),
+		conventions: conventions,
+	}
+	for _, inst := range instruments {
+		r.instruments[inst.Symbol] = inst
+	}
+	return r
+}
+
+// Load builds the registry from configuration. Instruments come from the JSON file and
+// inline definitions; any configured trading pair without a definition is added with
+// its base and quote taken from the symbol. Per-exchange asset aliases extend the
+// default symbol conventions.
+func Load(cfg *config.Config) (*Registry, error) {
+	var definitions []config.InstrumentConfig
+	if cfg.Instruments.File != "" {
+		data, err := os.ReadFile(cfg.Instruments.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read instruments file: %w", err)
+		}
+		if err := json.Unmarshal(data, &definitions); err != nil {
+			return nil, fmt.Errorf("failed to parse instruments file: %w", err)
+		}
+	}
+	definitions = append(definitions, cfg.Instruments.Definitions...)
+
+	instruments := make(map[string]Instrument, len(definitions))
+	for _, def := range definitions {
+		inst, err := fromConfig(def)
+		if err != nil {
+			return nil, err
+		}
+		instruments[inst.Symbol] = inst
+	}
+
+	pairs := cfg.Arbitrage.Pairs()
+	for _, exchangeCfg := range cfg.Exchanges {
+		pairs = append(pairs, exchangeCfg.Pairs...)
+	}
+	for _, pair := range pairs {
+		if _, ok := instruments[pair]; ok {
+			continue
+		}
+		inst, err := fromConfig(config.InstrumentConfig{Symbol: pair})
+		if err != nil {
+			return nil, err
+		}
+		instruments[pair] = inst
+	}
+
+	conventions := make(map[string]Convention, len(defaultConventions))
+	for exchange, convention := range defaultConventions {
+		conventions[exchange] = convention
+	}
+	for exchange, exchangeCfg := range cfg.Exchanges {
+		if len(exchangeCfg.AssetAliases) == 0 {
+			continue
+		}
+		convention := conventions[exchange]
+		aliases := make(map[string]string, len(convention.Aliases)+len(exchangeCfg.AssetAliases))
+		for asset, alias := range convention.Aliases {
+			aliases[asset] = alias
+		}
+		// Viper lowercases map keys, so asset codes are normalised here
+		for asset, alias := range exchangeCfg.AssetAliases {
+			aliases[strings.ToUpper(asset)] = strings.ToUpper(alias)
+		}
+		convention.Aliases = aliases
+		conventions[exchange] = convention
+	}
+
+	list := make([]Instrument, 0, len(instruments))
+	for _, inst := range instruments {
+		list = append(list, inst)
+	}
+	return NewRegistry(list, conventions), nil
+}
+
+// fromConfig validates a configured instrument, deriving base and quote from the symbol if unset.
+func fromConfig(def config.InstrumentConfig) (Instrument, error) {
+	base, quote, ok := strings.Cut(def.Symbol, "/")
+	if !ok || base == "" || quote == "" {
+		return Instrument{}, fmt.Errorf("invalid instrument symbol %q, expected BASE/QUOTE", def.Symbol)
+	}
+	inst := Instrument{
+		Symbol:    def.Symbol,
+		Base:      strings.ToUpper(base),
+		Quote:     strings.ToUpper(quote),
+		Exchanges: make(map[string]Listing, len(def.Exchanges)),
+	}
+	if def.Base != "" {
+		inst.Base = strings.ToUpper(def.Base)
+	}
+	if def.Quote != "" {
+		inst.Quote = strings.ToUpper(def.Quote)
+	}
+	for exchange, listing := range def.Exchanges {
+		inst.Exchanges[exchange] = Listing(listing)
+	}
+	return inst, nil
+}
+
+// Get returns the instrument with the given canonical symbol.
+func (r *Registry) Get(symbol string) (Instrument, bool) {
+	inst, ok := r.instruments[symbol]
+	return inst, ok
+}
+
+// All returns every instrument sorted by symbol.
+func (r *Registry) All() []Instrument {
+	list := make([]Instrument, 0, len(r.instruments))
+	for _, inst := range r.instruments {
+		list = append(list, inst)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Symbol < list[j].Symbol })
+	return list
+}
+
+// Listing returns how the instrument trades on the exchange, with the native
+// symbol filled in from the exchange's convention if it is not configured.
+func (r *Registry) Listing(exchange, symbol string) (Listing, error) {
+	inst, ok := r.instruments[symbol]
+	if !ok {
+		return Listing{}, fmt.Errorf("unknown instrument: %s", symbol)
+	}
+	listing := inst.Exchanges[exchange]
+	if listing.Symbol == "" {
+		convention, ok := r.conventions[exchange]
+		if !ok {
+			convention = Convention{Separator: "/"}
+		}
+		listing.Symbol = convention.alias(inst.Base) + convention.Separator + convention.alias(inst.Quote)
+	}
+	return listing, nil
+}
+
+// Native returns the exchange's native symbol for a canonical instrument.
+func (r *Registry) Native(exchange, symbol string) (string, error) {
+	listing, err := r.Listing(exchange, symbol)
+	if err != nil {
+		return "", err
+	}
+	return listing.Symbol, nil
+}
+
+// Canonical returns the canonical symbol for an exchange's native symbol.
+// Native symbols are matched case-insensitively.
+func (r *Registry) Canonical(exchange, native string) (string, bool) {
+	for symbol := range r.instruments {
+		if candidate, err := r.Native(exchange, symbol); err == nil && strings.EqualFold(candidate, native) {
+			return symbol, true
+		}
+	}
+	return "", false
+}
+
+func (c Convention) alias(asset string) string {
+	if alias, ok := c.Aliases[asset]; ok {
+		return alias
+	}
+	return asset
+}
@@ -0,0 +1,81 @@
+package instrument
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"referee/internal/config"
+)
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "instruments.json")
+	err := os.WriteFile(file, []byte(`[
+		{"symbol": "ETH/EUR", "exchanges": {"binance": {"lot_size": 0.0001, "min_notional": 5}}}
+	]`), 0o600)
+	assert.NoError(t, err)
+
+	cfg := &config.Config{
+		Arbitrage: config.ArbitrageConfig{TradingPairs: []string{"BTC/EUR", "ETH/EUR"}},
+		Exchanges: map[string]config.ExchangeConfig{
+			"kraken":  {AssetAliases: map[string]string{"doge": "xdg"}},
+			"binance": {Pairs: []string{"SOL/EUR"}},
+		},
+		Instruments: config.InstrumentsConfig{
+			File: file,
+			Definitions: []config.InstrumentConfig{
+				{Symbol: "BTC/EUR", Exchanges: map[string]config.ListingConfig{"binance": {Symbol: "BTCEUR", TickSize: 0.01}}},
+				{Symbol: "DOGE/EUR"},
+			},
+		},
+	}
+
+	registry, err := Load(cfg)
+	assert.NoError(t, err)
+	assert.Len(t, registry.All(), 4)
+
+	inst, ok := registry.Get("SOL/EUR")
+	assert.True(t, ok)
+	assert.Equal(t, "SOL", inst.Base)
+	assert.Equal(t, "EUR", inst.Quote)
+
+	t.Run("explicit listing", func(t *testing.T) {
+		listing, err := registry.Listing("binance", "BTC/EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, "BTCEUR", listing.Symbol)
+		assert.Equal(t, 0.01, listing.TickSize)
+	})
+
+	t.Run("listing from file with derived symbol", func(t *testing.T) {
+		listing, err := registry.Listing("binance", "ETH/EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, "ETHEUR", listing.Symbol)
+		assert.Equal(t, 5.0, listing.MinNotional)
+	})
+
+	t.Run("exchange conventions and aliases", func(t *testing.T) {
+		native, err := registry.Native("kraken", "BTC/EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, "XBT/EUR", native)
+
+		native, err = registry.Native("kraken", "DOGE/EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, "XDG/EUR", native)
+
+		pair, ok := registry.Canonical("kraken", "XBT/EUR")
+		assert.True(t, ok)
+		assert.Equal(t, "BTC/EUR", pair)
+	})
+
+	t.Run("unknown instrument", func(t *testing.T) {
+		_, err := registry.Native("kraken", "ADA/EUR")
+		assert.Error(t, err)
+	})
+}
+
+func TestLoad_InvalidSymbol(t *testing.T) {
+	cfg := &config.Config{Arbitrage: config.ArbitrageConfig{TradingPairs: []string{"BTCEUR"}}}
+	_, err := Load(cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"referee/internal/model"
+)
+
+// ErrInsufficientBalance is returned when a trade would overdraw an exchange balance.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// Inventory tracks the assets held on every exchange. Funds are pre-positioned, so
+// trades move balances within each exchange and nothing is withdrawn per trade.
+type Inventory struct {
+	balances  map[string]map[string]float64 // exchange -> asset -> amount
+	inTransit map[string]float64            // asset -> amount moving between exchanges
+}
+
+// New creates an inventory with the given starting balances per exchange and asset.
+// Asset codes are normalised to upper case.
+func New(balances map[string]map[string]float64) *Inventory {
+	inv := &Inventory{
+		balances:  make(map[string]map[string]float64, len(balances)),
+		inTransit: make(map[string]float64),
+	}
+	for exchange, assets := range balances {
+		for asset, amount := range assets {
+			inv.add(exchange, asset, amount)
+		}
+	}
+	return inv
+}
+
+// Balance returns the amount of the asset held on the exchange.
+func (inv *Inventory) Balance(exchange, asset string) float64 {
+	return inv.balances[exchange][strings.ToUpper(asset)]
+}
+
+// InTransit returns the amount of the asset that has left one exchange and not yet
+// arrived at another. It is not available for trading.
+func (inv *Inventory) InTransit(asset string) float64 {
+	return inv.inTransit[strings.ToUpper(asset)]
+}
+
+// Exchanges returns the exchanges that hold a balance of the asset, sorted by name.
+func (inv *Inventory) Exchanges(asset string) []string {
+	asset = strings.ToUpper(asset)
+	var exchanges []string
+	for exchange, assets := range inv.balances {
+		if _, ok := assets[asset]; ok {
+			exchanges = append(exchanges, exchange)
+		}
+	}
+	sort.Strings(exchanges)
+	return exchanges
+}
+
+// StartTransfer withdraws the transfer amount from the source exchange. The funds are in
+// transit until CompleteTransfer is called.
+func (inv *Inventory) StartTransfer(transfer model.Transfer) error {
+	asset := strings.ToUpper(transfer.Asset)
+	if available := inv.balances[transfer.FromExchange][asset]; available < transfer.Amount {
+		return fmt.Errorf("%w: %s has %g %s, needs %g", ErrInsufficientBalance, transfer.FromExchange, available, asset, transfer.Amount)
+	}
+	inv.add(transfer.FromExchange, asset, -transfer.Amount)
+	inv.inTransit[asset] += transfer.Amount
+	return nil
+}
+
+// CompleteTransfer credits the destination exchange with the transfer amount net of its fee.
+func (inv *Inventory) CompleteTransfer(transfer model.Transfer) {
+	asset := strings.ToUpper(transfer.Asset)
+	inv.inTransit[asset] -= transfer.Amount
+	inv.add(transfer.ToExchange, asset, transfer.Amount-transfer.Fee)
+}
+
+// Check reports whether every leg of a trade can be funded from the current balances.
+func (inv *Inventory) Check(legs []model.TradeLeg) error {
+	required := make(map[[2]string]float64)
+	for _, change := range changes(legs) {
+		key := [2]string{change.Exchange, change.Asset}
+		required[key] += change.Amount
+	}
+	for key, delta := range required {
+		available := inv.balances[key[0]][key[1]]
+		if available+delta < -1e-9 {
+			return fmt.Errorf("%w: %s has %g %s, needs %g", ErrInsufficientBalance, key[0], available, key[1], -delta)
+		}
+	}
+	return nil
+}
+
+// Apply debits and credits every leg of a trade. Nothing is changed if any balance
+// would go negative.
+func (inv *Inventory) Apply(legs []model.TradeLeg) error {
+	if err := inv.Check(legs); err != nil {
+		return err
+	}
+	for _, change := range changes(legs) {
+		inv.add(change.Exchange, change.Asset, change.Amount)
+	}
+	return nil
+}
+
+// Snapshot returns the balances of the given exchanges, or of every exchange if none
+// are given, sorted by exchange and asset.
+func (inv *Inventory) Snapshot(at time.Time, exchanges ...string) []model.Balance {
+	if len(exchanges) == 0 {
+		for exchange := range inv.balances {
+			exchanges = append(exchanges, exchange)
+		}
+	}
+	var snapshot []model.Balance
+	for _, exchange := range exchanges {
+		for asset, amount := range inv.balances[exchange] {
+			snapshot = append(snapshot, model.Balance{Timestamp: at, Exchange: exchange, Asset: asset, Amount: amount})
+		}
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Exchange != snapshot[j].Exchange {
+			return snapshot[i].Exchange < snapshot[j].Exchange
+		}
+		return snapshot[i].Asset < snapshot[j].Asset
+	})
+	return snapshot
+}
+
+func (inv *Inventory) add(exchange, asset string, amount float64) {
+	assets, ok := inv.balances[exchange]
+	if !ok {
+		assets = make(map[string]float64)
+		inv.balances[exchange] = assets
+	}
+	assets[strings.ToUpper(asset)] += amount
+}
+
+// change is a signed movement of one asset on one exchange.
+type change struct {
+	Exchange string
+	Asset    string
+	Amount   float64
+}
+
+// changes converts trade legs into balance movements: a buy spends the quote asset and
+// receives the base asset, a sell does the opposite, and fees are debited on top.
+func changes(legs []model.TradeLeg) []change {
+	result := make([]change, 0, 3*len(legs))
+	for _, leg := range legs {
+		base, quote := strings.ToUpper(leg.BaseAsset), strings.ToUpper(leg.QuoteAsset)
+		if leg.Buy {
+			result = append(result,
+				change{Exchange: leg.Exchange, Asset: quote, Amount: -leg.QuoteQty},
+				change{Exchange: leg.Exchange, Asset: base, Amount: leg.BaseQty})
+		} else {
+			result = append(result,
+				change{Exchange: leg.Exchange, Asset: base, Amount: -leg.BaseQty},
+				change{Exchange: leg.Exchange, Asset: quote, Amount: leg.QuoteQty})
+		}
+		if leg.Fee != 0 {
+			result = append(result, change{Exchange: leg.Exchange, Asset: strings.ToUpper(leg.FeeAsset), Amount: -leg.Fee})
+		}
+	}
+	return result
+}
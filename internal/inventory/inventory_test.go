@@ -0,0 +1,79 @@
+package inventory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"referee/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInventory(t *testing.T) {
+	inv := New(map[string]map[string]float64{
+		"kraken":  {"eur": 10000, "BTC": 0.1},
+		"binance": {"EUR": 10000, "BTC": 0.1},
+	})
+	assert.Equal(t, 10000.0, inv.Balance("kraken", "EUR"))
+
+	// Buy 0.05 BTC on Kraken, sell it on Binance, paying the fees in EUR
+	legs := []model.TradeLeg{
+		{Exchange: "kraken", BaseAsset: "BTC", QuoteAsset: "EUR", Buy: true, BaseQty: 0.05, QuoteQty: 3000, Fee: 7.8, FeeAsset: "EUR"},
+		{Exchange: "binance", BaseAsset: "BTC", QuoteAsset: "EUR", Buy: false, BaseQty: 0.05, QuoteQty: 3050, Fee: 3.05, FeeAsset: "EUR"},
+	}
+	assert.NoError(t, inv.Apply(legs))
+	assert.InDelta(t, 6992.2, inv.Balance("kraken", "EUR"), 1e-9)
+	assert.InDelta(t, 0.15, inv.Balance("kraken", "BTC"), 1e-9)
+	assert.InDelta(t, 13046.95, inv.Balance("binance", "EUR"), 1e-9)
+	assert.InDelta(t, 0.05, inv.Balance("binance", "BTC"), 1e-9)
+
+	// Binance only has 0.05 BTC left to sell, so a second trade is blocked and changes nothing
+	legs[1].BaseQty = 0.06
+	err := inv.Apply(legs)
+	assert.True(t, errors.Is(err, ErrInsufficientBalance))
+	assert.InDelta(t, 6992.2, inv.Balance("kraken", "EUR"), 1e-9)
+
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := inv.Snapshot(at, "binance")
+	assert.Equal(t, []model.Balance{
+		{Timestamp: at, Exchange: "binance", Asset: "BTC", Amount: inv.Balance("binance", "BTC")},
+		{Timestamp: at, Exchange: "binance", Asset: "EUR", Amount: inv.Balance("binance", "EUR")},
+	}, snapshot)
+	assert.Len(t, inv.Snapshot(at), 4)
+}
+
+func TestRebalancer(t *testing.T) {
+	inv := New(map[string]map[string]float64{
+		"kraken":  {"EUR": 9000, "BTC": 0.2},
+		"binance": {"EUR": 1000, "BTC": 0.2},
+	})
+	rebalancer := NewRebalancer(0.75, map[string]TransferCost{
+		"eur": {Fee: 1, Delay: time.Hour},
+		"BTC": {Fee: 0.0001, Delay: 30 * time.Minute},
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Kraken holds 90% of the EUR, BTC is evenly split
+	transfers := rebalancer.Plan(inv, now)
+	assert.Equal(t, []model.Transfer{{
+		InitiatedAt:  now,
+		ArrivesAt:    now.Add(time.Hour),
+		Asset:        "EUR",
+		FromExchange: "kraken",
+		ToExchange:   "binance",
+		Amount:       4000,
+		Fee:          1,
+	}}, transfers)
+
+	// Funds in transit are unavailable and block further transfers of the asset
+	assert.NoError(t, inv.StartTransfer(transfers[0]))
+	assert.Equal(t, 5000.0, inv.Balance("kraken", "EUR"))
+	assert.Equal(t, 4000.0, inv.InTransit("EUR"))
+	assert.Empty(t, rebalancer.Plan(inv, now))
+
+	inv.CompleteTransfer(transfers[0])
+	assert.Equal(t, 4999.0, inv.Balance("binance", "EUR"))
+	assert.Equal(t, 0.0, inv.InTransit("EUR"))
+	assert.Empty(t, rebalancer.Plan(inv, now))
+}
@@ -0,0 +1,89 @@
+package inventory
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"referee/internal/model"
+)
+
+// TransferCost describes what it takes to move an asset between exchanges, e.g. an
+// on-chain withdrawal for BTC or a SEPA transfer for EUR.
+type TransferCost struct {
+	Fee   float64       // Charged in the asset being moved
+	Delay time.Duration // Time until the funds are credited at the destination
+}
+
+// Rebalancer plans transfers that move skewed assets back towards an even split
+// between exchanges.
+type Rebalancer struct {
+	threshold float64
+	costs     map[string]TransferCost
+}
+
+// NewRebalancer creates a rebalancer for the assets with a configured transfer cost.
+// An asset is skewed when one exchange holds more than threshold (0-1) of its total.
+func NewRebalancer(threshold float64, costs map[string]TransferCost) *Rebalancer {
+	normalised := make(map[string]TransferCost, len(costs))
+	for asset, cost := range costs {
+		normalised[strings.ToUpper(asset)] = cost
+	}
+	return &Rebalancer{threshold: threshold, costs: normalised}
+}
+
+// Plan returns the transfers to start at now. Each skewed asset is moved from the
+// exchange holding the most to the one holding the least, by as much as brings either
+// of them to an even share. Assets that already have funds in transit are left alone
+// until they arrive, and transfers that would not cover their fee are skipped.
+func (r *Rebalancer) Plan(inv *Inventory, now time.Time) []model.Transfer {
+	assets := make([]string, 0, len(r.costs))
+	for asset := range r.costs {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+
+	var transfers []model.Transfer
+	for _, asset := range assets {
+		if inv.InTransit(asset) > 0 {
+			continue
+		}
+		exchanges := inv.Exchanges(asset)
+		if len(exchanges) < 2 {
+			continue
+		}
+
+		var total float64
+		richest, poorest := exchanges[0], exchanges[0]
+		for _, exchange := range exchanges {
+			balance := inv.Balance(exchange, asset)
+			total += balance
+			if balance > inv.Balance(richest, asset) {
+				richest = exchange
+			}
+			if balance < inv.Balance(poorest, asset) {
+				poorest = exchange
+			}
+		}
+		if total <= 0 || inv.Balance(richest, asset)/total <= r.threshold {
+			continue
+		}
+
+		target := total / float64(len(exchanges))
+		amount := min(inv.Balance(richest, asset)-target, target-inv.Balance(poorest, asset))
+		cost := r.costs[asset]
+		if amount <= cost.Fee {
+			continue
+		}
+		transfers = append(transfers, model.Transfer{
+			InitiatedAt:  now,
+			ArrivesAt:    now.Add(cost.Delay),
+			Asset:        asset,
+			FromExchange: richest,
+			ToExchange:   poorest,
+			Amount:       amount,
+			Fee:          cost.Fee,
+		})
+	}
+	return transfers
+}
@@ -0,0 +1,78 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+
+	"referee/internal/model"
+)
+
+// OpeningEntry books the starting balances against opening equity.
+func OpeningEntry(at time.Time, balances []model.Balance) model.LedgerEntry {
+	entry := model.LedgerEntry{Timestamp: at, Kind: KindOpening, Reference: "starting balances"}
+	for _, balance := range balances {
+		entry.Postings = append(entry.Postings,
+			model.Posting{Account: accountAssets + balance.Exchange, Asset: balance.Asset, Amount: balance.Amount},
+			model.Posting{Account: accountOpeningEquity, Asset: balance.Asset, Amount: -balance.Amount},
+		)
+	}
+	return entry
+}
+
+// TradeEntry books every leg of an executed trade and the fees it paid.
+func TradeEntry(at time.Time, trade model.SimulatedTrade) model.LedgerEntry {
+	reference := fmt.Sprintf("%s %s %s>%s", trade.Strategy, trade.TradingPair, trade.BuyExchange, trade.SellExchange)
+	if trade.Route != "" {
+		reference = fmt.Sprintf("%s %s on %s", trade.Strategy, trade.Route, trade.BuyExchange)
+	}
+	entry := model.LedgerEntry{Timestamp: at, Kind: KindTrade, Reference: reference}
+	for _, leg := range trade.Legs {
+		assets, trading := accountAssets+leg.Exchange, accountTrading+leg.Exchange
+		received, paid := leg.BaseAsset, leg.QuoteAsset
+		receivedQty, paidQty := leg.BaseQty, leg.QuoteQty
+		if !leg.Buy {
+			received, paid = leg.QuoteAsset, leg.BaseAsset
+			receivedQty, paidQty = leg.QuoteQty, leg.BaseQty
+		}
+		entry.Postings = append(entry.Postings,
+			model.Posting{Account: assets, Asset: received, Amount: receivedQty},
+			model.Posting{Account: trading, Asset: received, Amount: -receivedQty},
+			model.Posting{Account: assets, Asset: paid, Amount: -paidQty},
+			model.Posting{Account: trading, Asset: paid, Amount: paidQty},
+		)
+		if leg.Fee != 0 {
+			entry.Postings = append(entry.Postings,
+				model.Posting{Account: assets, Asset: leg.FeeAsset, Amount: -leg.Fee},
+				model.Posting{Account: accountFees + leg.Exchange, Asset: leg.FeeAsset, Amount: leg.Fee},
+			)
+		}
+	}
+	return entry
+}
+
+// TransferOutEntry moves the funds of a transfer from the source exchange into transit.
+func TransferOutEntry(transfer model.Transfer) model.LedgerEntry {
+	return model.LedgerEntry{
+		Timestamp: transfer.InitiatedAt,
+		Kind:      KindTransferOut,
+		Reference: fmt.Sprintf("%s %s>%s", transfer.Asset, transfer.FromExchange, transfer.ToExchange),
+		Postings: []model.Posting{
+			{Account: accountAssets + transfer.FromExchange, Asset: transfer.Asset, Amount: -transfer.Amount},
+			{Account: accountInTransit, Asset: transfer.Asset, Amount: transfer.Amount},
+		},
+	}
+}
+
+// TransferInEntry credits the destination exchange with an arrived transfer and books its fee.
+func TransferInEntry(transfer model.Transfer) model.LedgerEntry {
+	return model.LedgerEntry{
+		Timestamp: transfer.ArrivesAt,
+		Kind:      KindTransferIn,
+		Reference: fmt.Sprintf("%s %s>%s", transfer.Asset, transfer.FromExchange, transfer.ToExchange),
+		Postings: []model.Posting{
+			{Account: accountInTransit, Asset: transfer.Asset, Amount: -transfer.Amount},
+			{Account: accountAssets + transfer.ToExchange, Asset: transfer.Asset, Amount: transfer.Amount - transfer.Fee},
+			{Account: accountTransferFees, Asset: transfer.Asset, Amount: transfer.Fee},
+		},
+	}
+}
@@ -0,0 +1,164 @@
+package ledger
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"referee/internal/model"
+)
+
+// Entry kinds recorded in the ledger.
+const (
+	KindOpening     = "opening"
+	KindTrade       = "trade"
+	KindTransferOut = "transfer_out"
+	KindTransferIn  = "transfer_in"
+)
+
+// Account names. Exchange holdings live in "assets:<exchange>"; the other side of every
+// fill is booked to "trading:<exchange>", so multi-currency entries stay balanced per asset.
+const (
+	accountAssets        = "assets:"
+	accountInTransit     = "assets:in_transit"
+	accountTrading       = "trading:"
+	accountFees          = "expenses:fees:"
+	accountTransferFees  = "expenses:transfers"
+	accountOpeningEquity = "equity:opening"
+)
+
+// Valuer converts an amount of an asset into EUR. It returns false if no price is known.
+type Valuer func(asset string, amount float64) (float64, bool)
+
+// Ledger is a double-entry record of the simulated inventory. It tracks realized PnL as
+// trades execute and derives equity, unrealized PnL and drawdown from mark-to-market
+// valuations of the holdings.
+type Ledger struct {
+	nextID   int64
+	balances map[string]map[string]float64 // account -> asset -> amount
+	opening  map[string]float64            // asset -> amount held at the start
+	realized float64
+
+	valued      bool // False until the first successful mark-to-market
+	peakEquity  float64
+	maxDrawdown float64
+}
+
+// New creates an empty ledger. Entry IDs are only unique within the ledger; the
+// repository scopes them to the run.
+func New() *Ledger {
+	return &Ledger{
+		nextID:   1,
+		balances: make(map[string]map[string]float64),
+		opening:  make(map[string]float64),
+	}
+}
+
+// Record validates that the entry balances for every asset, assigns its ID and posts it.
+func (l *Ledger) Record(entry model.LedgerEntry) (model.LedgerEntry, error) {
+	totals := make(map[string]float64)
+	for _, posting := range entry.Postings {
+		totals[posting.Asset] += posting.Amount
+	}
+	for asset, total := range totals {
+		if math.Abs(total) > 1e-9 {
+			return model.LedgerEntry{}, fmt.Errorf("unbalanced %s entry: %s postings sum to %g", entry.Kind, asset, total)
+		}
+	}
+
+	entry.ID = l.nextID
+	l.nextID++
+	for _, posting := range entry.Postings {
+		assets, ok := l.balances[posting.Account]
+		if !ok {
+			assets = make(map[string]float64)
+			l.balances[posting.Account] = assets
+		}
+		assets[posting.Asset] += posting.Amount
+		if entry.Kind == KindOpening && strings.HasPrefix(posting.Account, accountAssets) {
+			l.opening[posting.Asset] += posting.Amount
+		}
+	}
+	return entry, nil
+}
+
+// Realize adds to the realized PnL, in EUR.
+func (l *Ledger) Realize(eur float64) {
+	l.realized += eur
+}
+
+// Balance returns the balance of the asset in the account.
+func (l *Ledger) Balance(account, asset string) float64 {
+	return l.balances[account][asset]
+}
+
+// Holdings returns the total of every asset held on exchanges or in transit.
+func (l *Ledger) Holdings() map[string]float64 {
+	holdings := make(map[string]float64)
+	for account, assets := range l.balances {
+		if !strings.HasPrefix(account, accountAssets) {
+			continue
+		}
+		for asset, amount := range assets {
+			holdings[asset] += amount
+		}
+	}
+	return holdings
+}
+
+// MarkToMarket values the holdings in EUR and updates peak equity and drawdown.
+// Unrealized PnL is the change in equity since the start that trades have not realized;
+// the starting holdings are valued at the current prices, so it measures what the
+// strategy added rather than market moves of the initial inventory. It returns false
+// if any asset cannot be valued.
+func (l *Ledger) MarkToMarket(at time.Time, value Valuer) (model.EquitySnapshot, bool) {
+	equity, ok := valueAll(l.Holdings(), value)
+	if !ok {
+		return model.EquitySnapshot{}, false
+	}
+	initial, ok := valueAll(l.opening, value)
+	if !ok {
+		return model.EquitySnapshot{}, false
+	}
+	if !l.valued || equity > l.peakEquity {
+		l.peakEquity = equity
+	}
+	l.valued = true
+	drawdown := l.peakEquity - equity
+	l.maxDrawdown = math.Max(l.maxDrawdown, drawdown)
+
+	return model.EquitySnapshot{
+		Timestamp:        at,
+		EquityEUR:        equity,
+		RealizedPnLEUR:   l.realized,
+		UnrealizedPnLEUR: equity - initial - l.realized,
+		PeakEquityEUR:    l.peakEquity,
+		DrawdownEUR:      drawdown,
+		MaxDrawdownEUR:   l.maxDrawdown,
+	}, true
+}
+
+// valueAll sums the EUR value of the amounts in a stable order.
+func valueAll(amounts map[string]float64, value Valuer) (float64, bool) {
+	assets := make([]string, 0, len(amounts))
+	for asset := range amounts {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+
+	var total float64
+	for _, asset := range assets {
+		amount := amounts[asset]
+		if math.Abs(amount) < 1e-12 {
+			continue
+		}
+		eur, ok := value(asset, amount)
+		if !ok {
+			return 0, false
+		}
+		total += eur
+	}
+	return total, true
+}
@@ -0,0 +1,78 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"referee/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedger(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New()
+	btcPrice := 60000.0
+	value := func(asset string, amount float64) (float64, bool) {
+		switch asset {
+		case "EUR":
+			return amount, true
+		case "BTC":
+			return amount * btcPrice, true
+		}
+		return 0, false
+	}
+
+	_, err := l.Record(OpeningEntry(at, []model.Balance{
+		{Exchange: "kraken", Asset: "EUR", Amount: 10000},
+		{Exchange: "binance", Asset: "BTC", Amount: 0.1},
+	}))
+	assert.NoError(t, err)
+
+	// Buy 0.05 BTC for 3000 EUR on Kraken and sell it for 3050 EUR on Binance
+	trade := model.SimulatedTrade{
+		Strategy: "spatial", TradingPair: "BTC/EUR", BuyExchange: "kraken", SellExchange: "binance",
+		Legs: []model.TradeLeg{
+			{Exchange: "kraken", BaseAsset: "BTC", QuoteAsset: "EUR", Buy: true, BaseQty: 0.05, QuoteQty: 3000, Fee: 3, FeeAsset: "EUR"},
+			{Exchange: "binance", BaseAsset: "BTC", QuoteAsset: "EUR", Buy: false, BaseQty: 0.05, QuoteQty: 3050, Fee: 2, FeeAsset: "EUR"},
+		},
+	}
+	entry, err := l.Record(TradeEntry(at, trade))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), entry.ID)
+	l.Realize(45)
+	assert.Equal(t, 6997.0, l.Balance("assets:kraken", "EUR"))
+	assert.Equal(t, 3.0, l.Balance("expenses:fees:kraken", "EUR"))
+
+	snapshot, ok := l.MarkToMarket(at, value)
+	assert.True(t, ok)
+	assert.InDelta(t, 10000+6000+45, snapshot.EquityEUR, 1e-9)
+	assert.InDelta(t, 45, snapshot.RealizedPnLEUR, 1e-9)
+	assert.InDelta(t, 0, snapshot.UnrealizedPnLEUR, 1e-9)
+
+	// Moving BTC costs a fee and leaves the holdings in transit until it arrives
+	transfer := model.Transfer{
+		InitiatedAt: at, ArrivesAt: at.Add(time.Hour), Asset: "BTC",
+		FromExchange: "kraken", ToExchange: "binance", Amount: 0.05, Fee: 0.001,
+	}
+	_, err = l.Record(TransferOutEntry(transfer))
+	assert.NoError(t, err)
+	l.Realize(-60)
+	assert.Equal(t, 0.05, l.Balance("assets:in_transit", "BTC"))
+	_, err = l.Record(TransferInEntry(transfer))
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.099, l.Holdings()["BTC"], 1e-9)
+
+	// A falling BTC price is a drawdown in equity
+	btcPrice = 50000
+	snapshot, ok = l.MarkToMarket(at.Add(time.Hour), value)
+	assert.True(t, ok)
+	assert.InDelta(t, 10045+0.099*50000, snapshot.EquityEUR, 1e-9)
+	assert.InDelta(t, 10045+0.1*60000, snapshot.PeakEquityEUR, 1e-9)
+	assert.InDelta(t, 16045-(10045+0.099*50000), snapshot.MaxDrawdownEUR, 1e-9)
+	assert.InDelta(t, -15, snapshot.RealizedPnLEUR, 1e-9)
+
+	// Unbalanced entries are rejected
+	_, err = l.Record(model.LedgerEntry{Kind: KindTrade, Postings: []model.Posting{{Account: "assets:kraken", Asset: "EUR", Amount: 1}}})
+	assert.Error(t, err)
+}
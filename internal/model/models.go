@@ -3,11 +3,21 @@ package model
 import "time"
 
 // PriceTick represents a single price update from an exchange.
+// Bid and Ask hold the top of book; Bids and Asks optionally carry the
+// order book depth, best level first. Ticks without depth are treated as
+// having unlimited liquidity at the top of book.
+// ExchangeTime is the event time reported by the exchange and ReceivedAt is
+// the local time the message was read off the socket; either may be zero if unknown.
 type PriceTick struct {
-	Exchange string
-	Pair     string
-	Bid      float64
-	Ask      float64
+	Exchange     string
+	Pair         string
+	Bid          float64
+	Ask          float64
+	Bids         []PriceLevel
+	Asks         []PriceLevel
+	Sequence     int64
+	ExchangeTime time.Time
+	ReceivedAt   time.Time
 }
 
 // SimulatedTrade represents a completed arbitrage trade to be logged.
@@ -23,4 +33,180 @@ type SimulatedTrade struct {
 	GrossProfitEUR float64   `db:"gross_profit_eur"`
 	TotalFeesEUR   float64   `db:"total_fees_eur"`
 	NetProfitEUR   float64   `db:"net_profit_eur"`
+	// Effective fill prices and book impact from walking the order book
+	BuyVWAP            float64 `db:"buy_vwap"`
+	SellVWAP           float64 `db:"sell_vwap"`
+	BuyLevelsConsumed  int     `db:"buy_levels_consumed"`
+	SellLevelsConsumed int     `db:"sell_levels_consumed"`
+	SlippageEUR        float64 `db:"slippage_eur"`
+	// WithdrawalFeeEUR is the cost of moving the coins between exchanges, included in TotalFeesEUR
+	WithdrawalFeeEUR float64 `db:"withdrawal_fee_eur"`
+	// Market state when the opportunity was first identified, before simulated latency
+	DetectedAt        time.Time `db:"detected_at"`
+	DetectedBuyPrice  float64   `db:"detected_buy_price"`
+	DetectedSellPrice float64   `db:"detected_sell_price"`
+	Status            string    `db:"status"`
+	// TradeType is "spatial" or "triangular"; Route lists the assets of a triangular cycle
+	TradeType string `db:"trade_type"`
+	Route     string `db:"route"`
+	// Strategy names the strategy instance that found the trade, StrategyParams its parameter set
+	Strategy       string `db:"strategy"`
+	StrategyParams string `db:"strategy_params"`
+	// ProfitCurve samples the expected net profit across the sizes considered when the
+	// trade was detected; empty unless trade sizing is enabled
+	ProfitCurve []ProfitSample `db:"profit_curve"`
+	// EpisodeID links the trade to the episode of the opportunity it executed
+	EpisodeID int64 `db:"episode_id"`
+	// Legs are the individual fills making up the trade; they are not persisted
+	Legs []TradeLeg `db:"-"`
+}
+
+// SpreadPercent is the gross profit as a share of the volume; unfillable trades have none.
+func (t SimulatedTrade) SpreadPercent() float64 {
+	if t.VolumeEUR <= 0 {
+		return 0
+	}
+	return t.GrossProfitEUR / t.VolumeEUR * 100
+}
+
+// ProfitAsset is the asset the volume and profit figures are denominated in: the quote
+// asset of a spatial trade, or the start asset of a triangular cycle. It is the asset
+// the first leg pays, so it is empty for trades without legs.
+func (t SimulatedTrade) ProfitAsset() string {
+	if len(t.Legs) == 0 {
+		return ""
+	}
+	first := t.Legs[0]
+	if first.Buy {
+		return first.QuoteAsset
+	}
+	return first.BaseAsset
+}
+
+// ProfitSample is the expected net profit of a trade at one volume.
+type ProfitSample struct {
+	VolumeEUR    float64 `json:"volume_eur"`
+	NetProfitEUR float64 `json:"net_profit_eur"`
+}
+
+// TradeLeg is one fill of a trade on a single exchange. BaseQty and QuoteQty are the
+// amounts exchanged before fees; Fee is charged in FeeAsset.
+type TradeLeg struct {
+	Exchange   string
+	Pair       string
+	BaseAsset  string
+	QuoteAsset string
+	Buy        bool
+	BaseQty    float64
+	QuoteQty   float64
+	Fee        float64
+	FeeAsset   string
+}
+
+// Balance is the amount of one asset held on one exchange at a point in time.
+type Balance struct {
+	Timestamp time.Time `db:"timestamp"`
+	Exchange  string    `db:"exchange"`
+	Asset     string    `db:"asset"`
+	Amount    float64   `db:"amount"`
+}
+
+// Transfer is a simulated movement of funds between exchanges. The destination is
+// credited with Amount minus Fee at ArrivesAt; FeeEUR values the fee when it was initiated.
+type Transfer struct {
+	InitiatedAt  time.Time `db:"initiated_at"`
+	ArrivesAt    time.Time `db:"arrives_at"`
+	Asset        string    `db:"asset"`
+	FromExchange string    `db:"from_exchange"`
+	ToExchange   string    `db:"to_exchange"`
+	Amount       float64   `db:"amount"`
+	Fee          float64   `db:"fee"`
+	FeeEUR       float64   `db:"fee_eur"`
+}
+
+// LedgerEntry is a balanced journal entry of the simulated ledger: for every asset,
+// the amounts of its postings sum to zero.
+type LedgerEntry struct {
+	ID        int64
+	Timestamp time.Time
+	Kind      string // "opening", "trade", "transfer_out" or "transfer_in"
+	Reference string
+	Postings  []Posting
+}
+
+// Posting moves an amount of one asset into (positive) or out of (negative) an account.
+type Posting struct {
+	Account string
+	Asset   string
+	Amount  float64
+}
+
+// EquitySnapshot is a mark-to-market valuation of all holdings in EUR.
+type EquitySnapshot struct {
+	Timestamp        time.Time `db:"timestamp"`
+	EquityEUR        float64   `db:"equity_eur"`
+	RealizedPnLEUR   float64   `db:"realized_pnl_eur"`
+	UnrealizedPnLEUR float64   `db:"unrealized_pnl_eur"`
+	PeakEquityEUR    float64   `db:"peak_equity_eur"`
+	DrawdownEUR      float64   `db:"drawdown_eur"`
+	MaxDrawdownEUR   float64   `db:"max_drawdown_eur"`
+}
+
+// Opportunity is a crossed-book event seen by a strategy, whether it was acted on or not.
+// The figures are those expected at detection; Reason records why the trade was or was
+// not executed.
+type Opportunity struct {
+	Timestamp      time.Time `db:"timestamp"`
+	Strategy       string    `db:"strategy"`
+	TradeType      string    `db:"trade_type"`
+	TradingPair    string    `db:"trading_pair"`
+	Route          string    `db:"route"`
+	BuyExchange    string    `db:"buy_exchange"`
+	SellExchange   string    `db:"sell_exchange"`
+	BuyPrice       float64   `db:"buy_price"`
+	SellPrice      float64   `db:"sell_price"`
+	VolumeEUR      float64   `db:"volume_eur"`
+	GrossProfitEUR float64   `db:"gross_profit_eur"`
+	TotalFeesEUR   float64   `db:"total_fees_eur"`
+	NetProfitEUR   float64   `db:"net_profit_eur"`
+	Reason         string    `db:"reason"`
+	EpisodeID      int64     `db:"episode_id"`
+}
+
+// Episode is an opportunity that persisted across ticks, from the tick its books first
+// crossed until the tick they uncrossed. Executions counts the trades it submitted,
+// whether they were executed or missed.
+type Episode struct {
+	ID                int64     `db:"id"`
+	Strategy          string    `db:"strategy"`
+	TradeType         string    `db:"trade_type"`
+	TradingPair       string    `db:"trading_pair"`
+	Route             string    `db:"route"`
+	BuyExchange       string    `db:"buy_exchange"`
+	SellExchange      string    `db:"sell_exchange"`
+	StartedAt         time.Time `db:"started_at"`
+	EndedAt           time.Time `db:"ended_at"`
+	Ticks             int       `db:"ticks"`
+	PeakSpreadPercent float64   `db:"peak_spread_percent"`
+	PeakNetProfitEUR  float64   `db:"peak_net_profit_eur"`
+	Executions        int       `db:"executions"`
+}
+
+// Duration is how long the episode's books stayed crossed.
+func (e Episode) Duration() time.Duration {
+	return e.EndedAt.Sub(e.StartedAt)
+}
+
+// RiskEvent records a risk limit blocking a trade or the kill switch changing state.
+// Action is "blocked", "halted" or "resumed"; the trade fields are empty for kill
+// switch changes.
+type RiskEvent struct {
+	Timestamp    time.Time `db:"timestamp"`
+	Rule         string    `db:"rule"`
+	Action       string    `db:"action"`
+	Strategy     string    `db:"strategy"`
+	TradingPair  string    `db:"trading_pair"`
+	BuyExchange  string    `db:"buy_exchange"`
+	SellExchange string    `db:"sell_exchange"`
+	Detail       string    `db:"detail"`
 }
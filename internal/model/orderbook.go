@@ -0,0 +1,118 @@
+package model
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrStaleUpdate is returned when an order book delta is older than the book state.
+var ErrStaleUpdate = errors.New("order book update is older than current sequence")
+
+// PriceLevel represents a single price level of an order book.
+type PriceLevel struct {
+	Price float64
+	Size  float64
+}
+
+// OrderBook maintains a local L2 order book built from a snapshot and subsequent deltas.
+type OrderBook struct {
+	Exchange string
+	Pair     string
+	Sequence int64
+	bids     map[float64]float64
+	asks     map[float64]float64
+}
+
+// NewOrderBook creates an empty order book for the given exchange and pair.
+func NewOrderBook(exchange, pair string) *OrderBook {
+	return &OrderBook{
+		Exchange: exchange,
+		Pair:     pair,
+		bids:     make(map[float64]float64),
+		asks:     make(map[float64]float64),
+	}
+}
+
+// ApplySnapshot replaces the whole book with the given levels.
+func (b *OrderBook) ApplySnapshot(bids, asks []PriceLevel, sequence int64) {
+	b.bids = make(map[float64]float64, len(bids))
+	b.asks = make(map[float64]float64, len(asks))
+	applyLevels(b.bids, bids)
+	applyLevels(b.asks, asks)
+	b.Sequence = sequence
+}
+
+// ApplyDelta updates the book with changed levels. A level with zero size is removed.
+// Deltas with a sequence number not newer than the book are rejected.
+func (b *OrderBook) ApplyDelta(bids, asks []PriceLevel, sequence int64) error {
+	if sequence <= b.Sequence {
+		return ErrStaleUpdate
+	}
+	applyLevels(b.bids, bids)
+	applyLevels(b.asks, asks)
+	b.Sequence = sequence
+	return nil
+}
+
+// Truncate drops every level beyond the given depth on both sides of the book.
+func (b *OrderBook) Truncate(depth int) {
+	for _, level := range b.Bids(0)[min(depth, len(b.bids)):] {
+		delete(b.bids, level.Price)
+	}
+	for _, level := range b.Asks(0)[min(depth, len(b.asks)):] {
+		delete(b.asks, level.Price)
+	}
+}
+
+// Bids returns up to depth bid levels sorted from best (highest) to worst.
+// A depth of zero returns every level.
+func (b *OrderBook) Bids(depth int) []PriceLevel {
+	return sortedLevels(b.bids, depth, func(a, c float64) bool { return a > c })
+}
+
+// Asks returns up to depth ask levels sorted from best (lowest) to worst.
+// A depth of zero returns every level.
+func (b *OrderBook) Asks(depth int) []PriceLevel {
+	return sortedLevels(b.asks, depth, func(a, c float64) bool { return a < c })
+}
+
+// Tick builds a PriceTick carrying the top of book and up to depth levels on each side.
+// The second return value is false if either side of the book is empty.
+func (b *OrderBook) Tick(depth int) (PriceTick, bool) {
+	bids := b.Bids(depth)
+	asks := b.Asks(depth)
+	if len(bids) == 0 || len(asks) == 0 {
+		return PriceTick{}, false
+	}
+	return PriceTick{
+		Exchange: b.Exchange,
+		Pair:     b.Pair,
+		Bid:      bids[0].Price,
+		Ask:      asks[0].Price,
+		Bids:     bids,
+		Asks:     asks,
+		Sequence: b.Sequence,
+	}, true
+}
+
+func applyLevels(side map[float64]float64, levels []PriceLevel) {
+	for _, level := range levels {
+		if level.Size == 0 {
+			delete(side, level.Price)
+			continue
+		}
+		side[level.Price] = level.Size
+	}
+}
+
+func sortedLevels(side map[float64]float64, depth int, better func(a, b float64) bool) []PriceLevel {
+	levels := make([]PriceLevel, 0, len(side))
+	for price, size := range side {
+		levels = append(levels, PriceLevel{Price: price, Size: size})
+	}
+	sort.Slice(levels, func(i, j int) bool { return better(levels[i].Price, levels[j].Price) })
+	if depth > 0 && len(levels) > depth {
+		levels = levels[:depth]
+	}
+	return levels
+}
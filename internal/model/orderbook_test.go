@@ -0,0 +1,55 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderBook(t *testing.T) {
+	book := NewOrderBook("kraken", "BTC/EUR")
+
+	t.Run("empty book has no tick", func(t *testing.T) {
+		_, ok := book.Tick(10)
+		assert.False(t, ok)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		book.ApplySnapshot(
+			[]PriceLevel{{Price: 59990, Size: 1}, {Price: 60000, Size: 0.5}},
+			[]PriceLevel{{Price: 60020, Size: 2}, {Price: 60010, Size: 0.25}},
+			100,
+		)
+
+		tick, ok := book.Tick(10)
+		assert.True(t, ok)
+		assert.Equal(t, 60000.0, tick.Bid)
+		assert.Equal(t, 60010.0, tick.Ask)
+		assert.Equal(t, []PriceLevel{{Price: 60000, Size: 0.5}, {Price: 59990, Size: 1}}, tick.Bids)
+		assert.Equal(t, []PriceLevel{{Price: 60010, Size: 0.25}, {Price: 60020, Size: 2}}, tick.Asks)
+		assert.Equal(t, int64(100), tick.Sequence)
+	})
+
+	t.Run("delta updates and removes levels", func(t *testing.T) {
+		err := book.ApplyDelta(
+			[]PriceLevel{{Price: 60000, Size: 0}, {Price: 60005, Size: 3}},
+			[]PriceLevel{{Price: 60010, Size: 1}},
+			101,
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, []PriceLevel{{Price: 60005, Size: 3}, {Price: 59990, Size: 1}}, book.Bids(0))
+		assert.Equal(t, []PriceLevel{{Price: 60010, Size: 1}}, book.Asks(1))
+	})
+
+	t.Run("stale delta is rejected", func(t *testing.T) {
+		err := book.ApplyDelta([]PriceLevel{{Price: 1, Size: 1}}, nil, 101)
+		assert.ErrorIs(t, err, ErrStaleUpdate)
+		assert.Len(t, book.Bids(0), 2)
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		book.Truncate(1)
+		assert.Equal(t, []PriceLevel{{Price: 60005, Size: 3}}, book.Bids(0))
+		assert.Equal(t, []PriceLevel{{Price: 60010, Size: 1}}, book.Asks(0))
+	})
+}
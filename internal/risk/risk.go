@@ -0,0 +1,192 @@
+package risk
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"referee/internal/config"
+	"referee/internal/model"
+)
+
+// Rules that can block a trade.
+const (
+	RuleKillSwitch     = "kill_switch"
+	RuleAbnormalSpread = "abnormal_spread"
+	RuleTradeRate      = "trade_rate"
+	RuleDailyLoss      = "daily_loss"
+	RuleExposure       = "exposure"
+)
+
+// Manager enforces the configured risk limits before every simulated execution. A
+// limit of zero is disabled. The manager is safe for concurrent use, so the kill switch
+// can be operated from outside the engine.
+//
+// A trade's figures are denominated in its profit asset, which is only EUR for EUR
+// pairs and cycles, so the caller passes the EUR value of the amounts checked against
+// the EUR limits.
+type Manager struct {
+	mu  sync.Mutex
+	cfg config.RiskConfig
+
+	halted     bool
+	haltReason string
+
+	submissions []time.Time // Submission times within the last minute, oldest first
+
+	day           time.Time // Start of the UTC day realizedToday belongs to
+	realizedToday float64
+
+	exposure map[string]float64 // Exchange -> notional traded and not yet moved off by rebalancing
+}
+
+// New creates a risk manager. The kill switch starts engaged if cfg.Halted is set.
+func New(cfg config.RiskConfig) *Manager {
+	m := &Manager{cfg: cfg, exposure: make(map[string]float64)}
+	if cfg.Halted {
+		m.halted, m.haltReason = true, "halted by configuration"
+	}
+	return m
+}
+
+// Check returns the rule that blocks the trade at now and why, or ok if no limit does.
+// volumeEUR is the trade's volume in EUR; valued is false if no price was available to
+// convert it, in which case the exposure limit blocks the trade.
+func (m *Manager) Check(now time.Time, trade model.SimulatedTrade, volumeEUR float64, valued bool) (rule, detail string, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.halted {
+		return RuleKillSwitch, m.haltReason, false
+	}
+	// Spreads this wide are far more likely to be bad data than free money
+	if limit := m.cfg.MaxSpreadPercent; limit > 0 && trade.SpreadPercent() > limit {
+		return RuleAbnormalSpread, fmt.Sprintf("spread %.4f%% exceeds %g%%", trade.SpreadPercent(), limit), false
+	}
+	if limit := m.cfg.MaxTradesPerMinute; limit > 0 && m.recentSubmissions(now) >= limit {
+		return RuleTradeRate, fmt.Sprintf("%d trades in the last minute", limit), false
+	}
+	if limit := m.cfg.MaxDailyLossEUR; limit > 0 && m.realized(now) <= -limit {
+		return RuleDailyLoss, fmt.Sprintf("realized %.2f EUR today, limit is -%g EUR", m.realized(now), limit), false
+	}
+	if limit := m.cfg.MaxExposureEUR; limit > 0 {
+		if !valued {
+			return RuleExposure, fmt.Sprintf("no price to value %s volume in EUR", trade.ProfitAsset()), false
+		}
+		for _, exchange := range exchanges(trade) {
+			if exposure := m.exposure[exchange] + volumeEUR; exposure > limit {
+				return RuleExposure, fmt.Sprintf("%s exposure would reach %.2f EUR, limit is %g EUR", exchange, exposure, limit), false
+			}
+		}
+	}
+	return "", "", true
+}
+
+// Submit records a trade that passed Check and was submitted for execution, with its
+// volume in EUR. The volume counts towards the exposure of every exchange it trades on
+// until Release moves the funds off.
+func (m *Manager) Submit(now time.Time, trade model.SimulatedTrade, volumeEUR float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.submissions = append(m.submissions, now)
+	for _, exchange := range exchanges(trade) {
+		m.exposure[exchange] += volumeEUR
+	}
+}
+
+// Settle records the outcome of a submitted trade. An executed trade adds its net
+// profit in EUR to the day's realized PnL and keeps its exposure; a missed one never
+// moved any funds, so the exposure Submit gave it is released.
+func (m *Manager) Settle(now time.Time, submitted model.SimulatedTrade, volumeEUR float64, executed bool, netProfitEUR float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if executed {
+		m.realize(now, netProfitEUR)
+		return
+	}
+	for _, exchange := range exchanges(submitted) {
+		m.exposure[exchange] = max(m.exposure[exchange]-volumeEUR, 0)
+	}
+}
+
+// Release reduces the exchange's exposure by funds worth eur that rebalancing moved off it.
+func (m *Manager) Release(exchange string, eur float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exposure[exchange] = max(m.exposure[exchange]-eur, 0)
+}
+
+// Realize adds PnL that does not come from trades, such as transfer fees, to the day's
+// realized PnL.
+func (m *Manager) Realize(now time.Time, eur float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.realize(now, eur)
+}
+
+// Halt engages the kill switch, blocking every trade until Resume is called. It returns
+// false if trading was already halted.
+func (m *Manager) Halt(reason string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.halted {
+		return false
+	}
+	m.halted, m.haltReason = true, reason
+	return true
+}
+
+// Resume releases the kill switch. It returns false if trading was not halted.
+func (m *Manager) Resume() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.halted {
+		return false
+	}
+	m.halted, m.haltReason = false, ""
+	return true
+}
+
+// Halted reports whether the kill switch is engaged, and why.
+func (m *Manager) Halted() (bool, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.halted, m.haltReason
+}
+
+// recentSubmissions drops submissions older than a minute and counts the rest.
+func (m *Manager) recentSubmissions(now time.Time) int {
+	cutoff := now.Add(-time.Minute)
+	expired := 0
+	for expired < len(m.submissions) && !m.submissions[expired].After(cutoff) {
+		expired++
+	}
+	m.submissions = m.submissions[expired:]
+	return len(m.submissions)
+}
+
+// realized returns the PnL realized on now's UTC day.
+func (m *Manager) realized(now time.Time) float64 {
+	if day := startOfDay(now); !day.Equal(m.day) {
+		m.day, m.realizedToday = day, 0
+	}
+	return m.realizedToday
+}
+
+func (m *Manager) realize(now time.Time, eur float64) {
+	m.realizedToday = m.realized(now) + eur
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// exchanges returns the distinct exchanges a trade is executed on.
+func exchanges(trade model.SimulatedTrade) []string {
+	if trade.BuyExchange == trade.SellExchange {
+		return []string{trade.BuyExchange}
+	}
+	return []string{trade.BuyExchange, trade.SellExchange}
+}
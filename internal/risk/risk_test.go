@@ -0,0 +1,154 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"referee/internal/config"
+	"referee/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager(t *testing.T) {
+	now := time.Date(2024, 1, 1, 23, 59, 0, 0, time.UTC)
+	trade := model.SimulatedTrade{BuyExchange: "kraken", SellExchange: "binance", VolumeEUR: 1000, GrossProfitEUR: 10}
+
+	t.Run("NoLimits", func(t *testing.T) {
+		m := New(config.RiskConfig{})
+		for range 100 {
+			_, _, ok := m.Check(now, trade, trade.VolumeEUR, true)
+			assert.True(t, ok)
+			m.Submit(now, trade, trade.VolumeEUR)
+		}
+	})
+
+	t.Run("TradeRate", func(t *testing.T) {
+		m := New(config.RiskConfig{MaxTradesPerMinute: 2})
+		m.Submit(now, trade, trade.VolumeEUR)
+		m.Submit(now.Add(30*time.Second), trade, trade.VolumeEUR)
+		rule, _, ok := m.Check(now.Add(45*time.Second), trade, trade.VolumeEUR, true)
+		assert.False(t, ok)
+		assert.Equal(t, RuleTradeRate, rule)
+
+		// The first submission falls out of the window a minute later
+		_, _, ok = m.Check(now.Add(time.Minute), trade, trade.VolumeEUR, true)
+		assert.True(t, ok)
+	})
+
+	t.Run("DailyLoss", func(t *testing.T) {
+		m := New(config.RiskConfig{MaxDailyLossEUR: 10})
+		m.Submit(now, trade, trade.VolumeEUR)
+		m.Settle(now, trade, trade.VolumeEUR, true, -6)
+		m.Realize(now, -4)
+		rule, _, ok := m.Check(now, trade, trade.VolumeEUR, true)
+		assert.False(t, ok)
+		assert.Equal(t, RuleDailyLoss, rule)
+
+		// Missed trades realize nothing, and the limit resets at midnight UTC
+		m.Settle(now, trade, trade.VolumeEUR, false, 100)
+		_, _, ok = m.Check(now, trade, trade.VolumeEUR, true)
+		assert.False(t, ok)
+		_, _, ok = m.Check(now.Add(time.Minute), trade, trade.VolumeEUR, true)
+		assert.True(t, ok)
+	})
+
+	t.Run("Exposure", func(t *testing.T) {
+		m := New(config.RiskConfig{MaxExposureEUR: 1500})
+		_, _, ok := m.Check(now, trade, trade.VolumeEUR, true)
+		assert.True(t, ok)
+		m.Submit(now, trade, trade.VolumeEUR)
+
+		// A second trade on Kraken would take its exposure to 2000 EUR
+		other := model.SimulatedTrade{BuyExchange: "bitstamp", SellExchange: "kraken", VolumeEUR: 1000}
+		rule, detail, ok := m.Check(now, other, other.VolumeEUR, true)
+		assert.False(t, ok)
+		assert.Equal(t, RuleExposure, rule)
+		assert.Contains(t, detail, "kraken")
+
+		// Missing the first trade releases its exposure
+		m.Settle(now, trade, trade.VolumeEUR, false, 0)
+		_, _, ok = m.Check(now, other, other.VolumeEUR, true)
+		assert.True(t, ok)
+	})
+
+	t.Run("ExposureAcrossSettledTrades", func(t *testing.T) {
+		m := New(config.RiskConfig{MaxExposureEUR: 2500})
+		// Trades that settle one after another still leave their funds on the exchanges
+		for i := range 2 {
+			at := now.Add(time.Duration(i) * time.Second)
+			_, _, ok := m.Check(at, trade, trade.VolumeEUR, true)
+			assert.True(t, ok)
+			m.Submit(at, trade, trade.VolumeEUR)
+			m.Settle(at.Add(10*time.Millisecond), trade, trade.VolumeEUR, true, 5)
+		}
+		rule, detail, ok := m.Check(now.Add(time.Minute), trade, trade.VolumeEUR, true)
+		assert.False(t, ok)
+		assert.Equal(t, RuleExposure, rule)
+		assert.Contains(t, detail, "3000.00 EUR")
+
+		// Rebalancing funds off Kraken only clears Kraken
+		m.Release("kraken", 1500)
+		rule, detail, ok = m.Check(now.Add(time.Minute), trade, trade.VolumeEUR, true)
+		assert.False(t, ok)
+		assert.Equal(t, RuleExposure, rule)
+		assert.Contains(t, detail, "binance")
+		m.Release("binance", 5000)
+		_, _, ok = m.Check(now.Add(time.Minute), trade, trade.VolumeEUR, true)
+		assert.True(t, ok)
+		assert.Equal(t, 0.0, m.exposure["binance"])
+	})
+
+	t.Run("ExposureInEUR", func(t *testing.T) {
+		m := New(config.RiskConfig{MaxExposureEUR: 1500})
+		// A cycle starting in BTC has its volume in BTC; 0.02 BTC is worth 1200 EUR
+		cycle := model.SimulatedTrade{
+			BuyExchange: "kraken", SellExchange: "kraken", VolumeEUR: 0.02,
+			Legs: []model.TradeLeg{{Exchange: "kraken", Pair: "ETH/BTC", BaseAsset: "ETH", QuoteAsset: "BTC", Buy: true}},
+		}
+		_, _, ok := m.Check(now, cycle, 1200, true)
+		assert.True(t, ok)
+		m.Submit(now, cycle, 1200)
+		rule, _, ok := m.Check(now, cycle, 1200, true)
+		assert.False(t, ok)
+		assert.Equal(t, RuleExposure, rule)
+
+		// Without a price the exposure cannot be checked, so the trade is blocked
+		m.Settle(now, cycle, 1200, false, 0)
+		rule, detail, ok := m.Check(now, cycle, 0, false)
+		assert.False(t, ok)
+		assert.Equal(t, RuleExposure, rule)
+		assert.Contains(t, detail, "BTC")
+	})
+
+	t.Run("AbnormalSpread", func(t *testing.T) {
+		m := New(config.RiskConfig{MaxSpreadPercent: 0.5})
+		rule, _, ok := m.Check(now, trade, trade.VolumeEUR, true)
+		assert.False(t, ok)
+		assert.Equal(t, RuleAbnormalSpread, rule)
+
+		small := model.SimulatedTrade{BuyExchange: "kraken", SellExchange: "binance", VolumeEUR: 1000, GrossProfitEUR: 4}
+		_, _, ok = m.Check(now, small, small.VolumeEUR, true)
+		assert.True(t, ok)
+	})
+
+	t.Run("KillSwitch", func(t *testing.T) {
+		m := New(config.RiskConfig{Halted: true})
+		halted, _ := m.Halted()
+		assert.True(t, halted)
+		rule, _, ok := m.Check(now, trade, trade.VolumeEUR, true)
+		assert.False(t, ok)
+		assert.Equal(t, RuleKillSwitch, rule)
+
+		assert.True(t, m.Resume())
+		assert.False(t, m.Resume())
+		_, _, ok = m.Check(now, trade, trade.VolumeEUR, true)
+		assert.True(t, ok)
+
+		assert.True(t, m.Halt("maintenance"))
+		assert.False(t, m.Halt("again"))
+		_, detail, ok := m.Check(now, trade, trade.VolumeEUR, true)
+		assert.False(t, ok)
+		assert.Equal(t, "maintenance", detail)
+	})
+}
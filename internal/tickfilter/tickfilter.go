@@ -0,0 +1,216 @@
+package tickfilter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"referee/internal/clock"
+	"referee/internal/config"
+	"referee/internal/model"
+)
+
+// Reasons a tick is rejected.
+const (
+	ReasonInvalidPrice = "invalid_price" // A bid or ask that is zero, negative or not a number
+	ReasonCrossed      = "crossed"       // The bid is above the ask
+	ReasonOutlier      = "outlier"       // The mid price deviates too far from the cross-exchange median
+)
+
+const (
+	defaultMaxDeviationPercent = 5.0
+	defaultWindow              = time.Minute
+	defaultMinExchanges        = 2
+)
+
+// reportInterval is how often the rejection counts are logged while the filter runs.
+const reportInterval = time.Minute
+
+// Rejection identifies a class of rejected ticks.
+type Rejection struct {
+	Exchange string
+	Reason   string
+}
+
+// sample is the mid price of an accepted tick.
+type sample struct {
+	at  time.Time
+	mid float64
+}
+
+// Filter validates ticks on their way from the exchange clients to the engine. It
+// rejects malformed quotes and quotes whose mid price deviates too far from the median
+// of the latest mid price of every exchange quoting the pair, so that a single bad tick
+// cannot show up as a large fake profit. Each exchange counts once however often it
+// updates, and only accepted ticks replace an exchange's mid price. An exchange's mid
+// price leaves the median once it is older than the window, so a genuine move is
+// accepted once the exchanges have followed it or the old prices have expired. It is
+// safe for concurrent use.
+type Filter struct {
+	logger       *slog.Logger
+	clock        clock.Clock
+	maxDeviation float64 // Percent; zero or less disables the outlier check
+	window       time.Duration
+	minExchanges int
+
+	mu       sync.Mutex
+	latest   map[string]map[string]sample // Pair -> exchange -> latest accepted mid price
+	accepted int64
+	rejected map[Rejection]int64
+}
+
+// New creates a filter, applying the defaults for unset configuration.
+func New(logger *slog.Logger, cfg config.TickFilterConfig, clk clock.Clock) *Filter {
+	f := &Filter{
+		logger:       logger,
+		clock:        clk,
+		maxDeviation: cfg.MaxDeviationPercent,
+		window:       time.Duration(cfg.WindowMS) * time.Millisecond,
+		minExchanges: cfg.MinExchanges,
+		latest:       make(map[string]map[string]sample),
+		rejected:     make(map[Rejection]int64),
+	}
+	if f.maxDeviation == 0 {
+		f.maxDeviation = defaultMaxDeviationPercent
+	}
+	if f.window <= 0 {
+		f.window = defaultWindow
+	}
+	if f.minExchanges <= 0 {
+		f.minExchanges = defaultMinExchanges
+	}
+	return f
+}
+
+// Run forwards the ticks from in that pass Check to out until the context is cancelled.
+// The rejection counts are logged every reportInterval and on shutdown.
+func (f *Filter) Run(ctx context.Context, in <-chan model.PriceTick, out chan<- model.PriceTick) error {
+	report := f.clock.After(reportInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			f.logRejected()
+			return ctx.Err()
+		case <-report:
+			f.logRejected()
+			report = f.clock.After(reportInterval)
+		case tick := <-in:
+			reason, detail, ok := f.Check(tick)
+			if !ok {
+				f.logger.Warn("Rejected tick", "exchange", tick.Exchange, "pair", tick.Pair, "reason", reason, "detail", detail,
+					"bid", tick.Bid, "ask", tick.Ask, "rejected", f.count(tick.Exchange, reason))
+				continue
+			}
+			select {
+			case out <- tick:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// Check validates a tick, returning why it was rejected if it was. An accepted tick
+// becomes its exchange's latest mid price for the pair.
+func (f *Filter) Check(tick model.PriceTick) (reason, detail string, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reason, detail, ok = f.check(tick)
+	if !ok {
+		f.rejected[Rejection{Exchange: tick.Exchange, Reason: reason}]++
+		return reason, detail, false
+	}
+	f.accepted++
+	return "", "", true
+}
+
+func (f *Filter) check(tick model.PriceTick) (reason, detail string, ok bool) {
+	if !validPrice(tick.Bid) || !validPrice(tick.Ask) {
+		return ReasonInvalidPrice, fmt.Sprintf("bid %g, ask %g", tick.Bid, tick.Ask), false
+	}
+	if tick.Bid > tick.Ask {
+		return ReasonCrossed, fmt.Sprintf("bid %g above ask %g", tick.Bid, tick.Ask), false
+	}
+
+	now := tick.ReceivedAt
+	if now.IsZero() {
+		now = f.clock.Now()
+	}
+	mid := (tick.Bid + tick.Ask) / 2
+	latest, ok := f.latest[tick.Pair]
+	if !ok {
+		latest = make(map[string]sample)
+		f.latest[tick.Pair] = latest
+	}
+	if f.maxDeviation > 0 {
+		if mids := f.recentMids(latest, now); len(mids) >= f.minExchanges {
+			median := median(mids)
+			if deviation := math.Abs(mid-median) / median * 100; deviation > f.maxDeviation {
+				return ReasonOutlier, fmt.Sprintf("mid %g deviates %.2f%% from median %g", mid, deviation, median), false
+			}
+		}
+	}
+	latest[tick.Exchange] = sample{at: now, mid: mid}
+	return "", "", true
+}
+
+// Accepted returns the number of ticks that passed the filter.
+func (f *Filter) Accepted() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.accepted
+}
+
+// Rejected returns the number of rejected ticks per exchange and reason.
+func (f *Filter) Rejected() map[Rejection]int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return maps.Clone(f.rejected)
+}
+
+// logRejected logs the number of rejected ticks per exchange and reason so far.
+func (f *Filter) logRejected() {
+	for rejection, count := range f.Rejected() {
+		f.logger.Info("Rejected ticks", "exchange", rejection.Exchange, "reason", rejection.Reason, "count", count)
+	}
+}
+
+func (f *Filter) count(exchange, reason string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rejected[Rejection{Exchange: exchange, Reason: reason}]
+}
+
+// recentMids returns the latest mid price of every exchange that has quoted the pair
+// within the window at now, dropping the exchanges whose price has expired.
+func (f *Filter) recentMids(latest map[string]sample, now time.Time) []float64 {
+	cutoff := now.Add(-f.window)
+	mids := make([]float64, 0, len(latest))
+	for exchange, s := range latest {
+		if !s.at.After(cutoff) {
+			delete(latest, exchange)
+			continue
+		}
+		mids = append(mids, s.mid)
+	}
+	return mids
+}
+
+func validPrice(price float64) bool {
+	return price > 0 && !math.IsInf(price, 0)
+}
+
+// median sorts the mid prices in place and returns their median.
+func median(mids []float64) float64 {
+	slices.Sort(mids)
+	if n := len(mids); n%2 == 0 {
+		return (mids[n/2-1] + mids[n/2]) / 2
+	}
+	return mids[len(mids)/2]
+}
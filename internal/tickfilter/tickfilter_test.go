@@ -0,0 +1,102 @@
+package tickfilter
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"referee/internal/clock"
+	"referee/internal/config"
+	"referee/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tick := func(exchange string, at time.Duration, bid, ask float64) model.PriceTick {
+		return model.PriceTick{Exchange: exchange, Pair: "BTC/EUR", Bid: bid, Ask: ask, ReceivedAt: start.Add(at)}
+	}
+
+	t.Run("rejects malformed quotes", func(t *testing.T) {
+		f := New(logger, config.TickFilterConfig{}, clock.NewSimulated(start))
+		for _, bad := range []model.PriceTick{
+			tick("kraken", 0, 0, 60001),
+			tick("kraken", 0, 60000, -1),
+			tick("kraken", 0, math.NaN(), 60001),
+			tick("kraken", 0, 60000, math.Inf(1)),
+		} {
+			reason, _, ok := f.Check(bad)
+			assert.False(t, ok)
+			assert.Equal(t, ReasonInvalidPrice, reason)
+		}
+		reason, _, ok := f.Check(tick("binance", 0, 60002, 60001))
+		assert.False(t, ok)
+		assert.Equal(t, ReasonCrossed, reason)
+
+		// A locked book is valid
+		_, _, ok = f.Check(tick("binance", 0, 60001, 60001))
+		assert.True(t, ok)
+
+		assert.Equal(t, int64(1), f.Accepted())
+		assert.Equal(t, map[Rejection]int64{
+			{Exchange: "kraken", Reason: ReasonInvalidPrice}: 4,
+			{Exchange: "binance", Reason: ReasonCrossed}:     1,
+		}, f.Rejected())
+	})
+
+	t.Run("rejects outliers from the cross-exchange median", func(t *testing.T) {
+		f := New(logger, config.TickFilterConfig{MaxDeviationPercent: 5, WindowMS: 10000, MinExchanges: 2}, clock.NewSimulated(start))
+
+		// Too few exchanges to judge a jump
+		_, _, ok := f.Check(tick("kraken", 0, 60000, 60002))
+		assert.True(t, ok)
+		_, _, ok = f.Check(tick("binance", time.Second, 60000, 60002))
+		assert.True(t, ok)
+		_, _, ok = f.Check(tick("bitstamp", 2*time.Second, 60500, 60502))
+		assert.True(t, ok)
+
+		// A 20% jump on one exchange is rejected, a move within the limit is not
+		reason, _, ok := f.Check(tick("binance", 3*time.Second, 72000, 72002))
+		assert.False(t, ok)
+		assert.Equal(t, ReasonOutlier, reason)
+		_, _, ok = f.Check(tick("binance", 3*time.Second, 62000, 62002))
+		assert.True(t, ok)
+
+		// However often Kraken updates, it counts once: the median stays at Bitstamp's 60501
+		for range 100 {
+			_, _, ok = f.Check(tick("kraken", 4*time.Second, 60000, 60002))
+			assert.True(t, ok)
+		}
+		_, _, ok = f.Check(tick("bitstamp", 5*time.Second, 63199, 63201))
+		assert.True(t, ok)
+
+		// A genuine move is accepted once the old prices have left the window
+		_, _, ok = f.Check(tick("kraken", 10500*time.Millisecond, 72000, 72002))
+		assert.False(t, ok)
+		_, _, ok = f.Check(tick("kraken", 16*time.Second, 72000, 72002))
+		assert.True(t, ok)
+	})
+
+	t.Run("forwards accepted ticks", func(t *testing.T) {
+		f := New(logger, config.TickFilterConfig{}, clock.NewSimulated(start))
+		in := make(chan model.PriceTick, 3)
+		out := make(chan model.PriceTick, 3)
+		in <- tick("kraken", 0, 60000, 60001)
+		in <- tick("kraken", 0, 60002, 60001)
+		in <- tick("binance", 0, 60000, 60001)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- f.Run(ctx, in, out) }()
+		assert.Equal(t, "kraken", (<-out).Exchange)
+		assert.Equal(t, "binance", (<-out).Exchange)
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+		assert.Empty(t, out)
+	})
+}